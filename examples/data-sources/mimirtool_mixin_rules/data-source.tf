@@ -0,0 +1,13 @@
+# Mixin vendored with jsonnet-bundler: jb install github.com/kubernetes-monitoring/kubernetes-mixin
+data "mimirtool_mixin_rules" "kubernetes" {
+  source = "${path.module}/mixins/vendor/kubernetes-mixin"
+  jpath  = ["${path.module}/mixins/vendor"]
+  ext_vars = {
+    cluster = "prod-eu"
+  }
+}
+
+resource "mimirtool_ruler_namespace" "kubernetes" {
+  namespace   = "kubernetes"
+  config_yaml = data.mimirtool_mixin_rules.kubernetes.config_yaml
+}
@@ -0,0 +1,7 @@
+data "mimirtool_ruler_namespace" "federated" {
+  namespace = "federated"
+}
+
+output "federated_source_tenants" {
+  value = data.mimirtool_ruler_namespace.federated.group_source_tenants
+}
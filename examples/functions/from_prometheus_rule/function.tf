@@ -0,0 +1,8 @@
+locals {
+  api_rules = provider::mimirtool::from_prometheus_rule(file("${path.module}/manifests/api-rules.yaml"))
+}
+
+resource "mimirtool_ruler_namespace" "api" {
+  namespace   = local.api_rules.namespace
+  config_yaml = local.api_rules.config_yaml
+}
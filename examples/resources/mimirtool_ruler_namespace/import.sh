@@ -1 +1,4 @@
 terraform import mimirtool_ruler_namespace.demo demo
+
+# Namespaces of the cortex and loki backends are prefixed by the backend
+terraform import mimirtool_ruler_namespace.logs loki:logs
@@ -12,3 +12,9 @@ groups:
     record: cluster_job:cortex_request_duration_seconds:50quantile
 EOT
 }
+
+# Rule groups spread over several files
+resource "mimirtool_ruler_namespace" "infra" {
+  namespace    = "infra"
+  config_files = ["${path.module}/rules/infra", "${path.module}/rules/common/*.yaml"]
+}
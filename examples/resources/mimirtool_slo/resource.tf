@@ -0,0 +1,37 @@
+resource "mimirtool_slo" "api_availability" {
+  namespace   = "slos"
+  name        = "api-availability"
+  good_query  = "sum(rate(http_requests_total{job=\"api\",code!~\"5..\"}[$window]))"
+  total_query = "sum(rate(http_requests_total{job=\"api\"}[$window]))"
+  objective   = 99.9
+
+  labels = {
+    team = "api"
+  }
+  annotations = {
+    runbook_url = "https://runbooks.example.com/api-availability"
+  }
+}
+
+resource "mimirtool_slo" "api_latency" {
+  namespace         = "slos"
+  name              = "api-latency"
+  error_ratio_query = "1 - (sum(rate(http_request_duration_seconds_bucket{job=\"api\",le=\"0.5\"}[$window])) / sum(rate(http_request_duration_seconds_count{job=\"api\"}[$window])))"
+  objective         = 99
+  time_window       = "28d"
+
+  alerting_windows = [
+    {
+      severity     = "critical"
+      long_window  = "1h"
+      short_window = "5m"
+      burn_rate    = 13.44
+    },
+    {
+      severity     = "warning"
+      long_window  = "1d"
+      short_window = "2h"
+      burn_rate    = 2.8
+    },
+  ]
+}
@@ -8,13 +8,13 @@ import (
 	"context"
 	"fmt"
 
-	"errors"
-
-	"github.com/grafana/mimir/pkg/mimirtool/client"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
@@ -40,7 +40,7 @@ func (r *AlertmanagerResource) Metadata(_ context.Context, req resource.Metadata
 	resp.TypeName = req.ProviderTypeName + "_alertmanager"
 }
 
-func (r *AlertmanagerResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+func (r *AlertmanagerResource) Schema(ctx context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
 		MarkdownDescription: "Manages the Alertmanager configuration in Grafana Mimir. [Official documentation](https://grafana.com/docs/mimir/latest/references/http-api/#alertmanager)",
 		Attributes: map[string]schema.Attribute{
@@ -63,6 +63,48 @@ func (r *AlertmanagerResource) Schema(_ context.Context, _ resource.SchemaReques
 				ElementType:         types.StringType,
 				Optional:            true,
 			},
+			"destroy_behavior": schema.StringAttribute{
+				MarkdownDescription: "What to do with the Alertmanager configuration when the resource is destroyed. " +
+					"`delete` (default) deletes it, which falls back to the tenant's fallback configuration. " +
+					"`keep` only removes the resource from the Terraform state. " +
+					"`restore` loads `restore_config_yaml` if set, otherwise the configuration found in Grafana Mimir when the resource was created.",
+				Optional: true,
+				Computed: true,
+				Default:  stringdefault.StaticString(destroyBehaviorDelete),
+				Validators: []validator.String{
+					stringOneOfValidator{values: []string{destroyBehaviorDelete, destroyBehaviorKeep, destroyBehaviorRestore}},
+				},
+			},
+			"restore_config_yaml": schema.StringAttribute{
+				MarkdownDescription: "The Alertmanager configuration to load when the resource is destroyed with `destroy_behavior = \"restore\"`.",
+				Optional:            true,
+				Validators: []validator.String{
+					yamlSyntaxValidator{},
+				},
+			},
+			"restore_templates_config_yaml": schema.MapAttribute{
+				MarkdownDescription: "The templates to load along with `restore_config_yaml`.",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"previous_config_yaml": schema.StringAttribute{
+				MarkdownDescription: "The Alertmanager configuration found in Grafana Mimir when the resource was created, restored on destroy with `destroy_behavior = \"restore\"` when `restore_config_yaml` is not set.",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"previous_templates_config_yaml": schema.MapAttribute{
+				MarkdownDescription: "The templates found in Grafana Mimir along with `previous_config_yaml`.",
+				ElementType:         types.StringType,
+				Computed:            true,
+				PlanModifiers: []planmodifier.Map{
+					mapplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeouts.Block(ctx, timeoutsOpts),
 		},
 	}
 }
@@ -71,21 +113,33 @@ func (r *AlertmanagerResource) Configure(_ context.Context, req resource.Configu
 	if req.ProviderData == nil {
 		return
 	}
-	client, ok := req.ProviderData.(mimirClientInterface)
+	c, ok := req.ProviderData.(*myClient)
 	if !ok {
 		resp.Diagnostics.AddError(
 			"Unexpected Resource Configure Type",
-			fmt.Sprintf("Expected mimirClientInterface, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+			fmt.Sprintf("Expected *myClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
 		)
 		return
 	}
-	r.client = client
+	r.client = c.cli
 }
 
+const (
+	destroyBehaviorDelete  = "delete"
+	destroyBehaviorKeep    = "keep"
+	destroyBehaviorRestore = "restore"
+)
+
 type AlertmanagerResourceModel struct {
-	ID                  types.String `tfsdk:"id"`
-	ConfigYAML          types.String `tfsdk:"config_yaml"`
-	TemplatesConfigYAML types.Map    `tfsdk:"templates_config_yaml"`
+	ID                          types.String   `tfsdk:"id"`
+	ConfigYAML                  types.String   `tfsdk:"config_yaml"`
+	TemplatesConfigYAML         types.Map      `tfsdk:"templates_config_yaml"`
+	DestroyBehavior             types.String   `tfsdk:"destroy_behavior"`
+	RestoreConfigYAML           types.String   `tfsdk:"restore_config_yaml"`
+	RestoreTemplatesConfigYAML  types.Map      `tfsdk:"restore_templates_config_yaml"`
+	PreviousConfigYAML          types.String   `tfsdk:"previous_config_yaml"`
+	PreviousTemplatesConfigYAML types.Map      `tfsdk:"previous_templates_config_yaml"`
+	Timeouts                    timeouts.Value `tfsdk:"timeouts"`
 }
 
 func (r *AlertmanagerResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
@@ -95,16 +149,35 @@ func (r *AlertmanagerResource) Create(ctx context.Context, req resource.CreateRe
 		return
 	}
 
+	createTimeout, diags := plan.Timeouts.Create(ctx, defaultTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, createTimeout)
+	defer cancel()
+
+	// Capture the configuration being replaced so that it can be restored on destroy
+	previousConfig, previousTemplates, err := r.client.GetAlertmanagerConfig(ctx)
+	switch {
+	case isNotFound(err):
+		plan.PreviousConfigYAML = types.StringNull()
+		plan.PreviousTemplatesConfigYAML = types.MapNull(types.StringType)
+	case err != nil:
+		addAPIErrorDiagnostic(&resp.Diagnostics, "read the current Alertmanager config before replacing it", err)
+		return
+	default:
+		plan.PreviousConfigYAML = types.StringValue(previousConfig)
+		plan.PreviousTemplatesConfigYAML = typeMapFromMapString(previousTemplates)
+	}
+
 	alertmanagerConfig := plan.ConfigYAML.ValueString()
 	templates := mapStringFromTypesMap(plan.TemplatesConfigYAML)
 
-	err := r.client.CreateAlertmanagerConfig(ctx, alertmanagerConfig, templates)
+	err = r.client.CreateAlertmanagerConfig(ctx, alertmanagerConfig, templates)
 	if err != nil {
 		tflog.Error(ctx, "Failed to create Alertmanager config via POST", map[string]interface{}{"error": err})
-		resp.Diagnostics.AddError(
-			"Error creating Alertmanager config",
-			fmt.Sprintf("Failed to create Alertmanager config via POST: %s", err),
-		)
+		addAPIErrorDiagnostic(&resp.Diagnostics, "create the Alertmanager config", err)
 		return
 	}
 
@@ -119,18 +192,23 @@ func (r *AlertmanagerResource) Read(ctx context.Context, req resource.ReadReques
 		return
 	}
 
+	readTimeout, diags := state.Timeouts.Read(ctx, defaultTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
 	alertmanagerConfig, templates, err := r.client.GetAlertmanagerConfig(ctx)
 	if err != nil {
-		if errors.Is(err, client.ErrResourceNotFound) {
+		if isNotFound(err) {
 			tflog.Info(ctx, "No alertmanager config found in backend; removing from state")
 			resp.State.RemoveResource(ctx)
 			return
 		}
 		tflog.Error(ctx, "Failed to read Alertmanager config", map[string]interface{}{"error": err})
-		resp.Diagnostics.AddError(
-			"Error reading Alertmanager config",
-			fmt.Sprintf("Failed to read Alertmanager config: %s", err),
-		)
+		addAPIErrorDiagnostic(&resp.Diagnostics, "read the Alertmanager config", err)
 		return
 	}
 
@@ -143,11 +221,24 @@ func (r *AlertmanagerResource) Read(ctx context.Context, req resource.ReadReques
 // The backend API does not support PUT for Alertmanager config updates.
 // Therefore, Update uses the same logic as Create (POST) to replace the configuration.
 func (r *AlertmanagerResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
-	var plan AlertmanagerResourceModel
+	var plan, state AlertmanagerResourceModel
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
+	// Only captured on create, UseStateForUnknown leaves them unknown when the
+	// state predates them or comes from an import
+	plan.PreviousConfigYAML = state.PreviousConfigYAML
+	plan.PreviousTemplatesConfigYAML = state.PreviousTemplatesConfigYAML
+
+	updateTimeout, diags := plan.Timeouts.Update(ctx, defaultTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, updateTimeout)
+	defer cancel()
 
 	alertmanagerConfig := plan.ConfigYAML.ValueString()
 	templates := mapStringFromTypesMap(plan.TemplatesConfigYAML)
@@ -155,10 +246,7 @@ func (r *AlertmanagerResource) Update(ctx context.Context, req resource.UpdateRe
 	err := r.client.CreateAlertmanagerConfig(ctx, alertmanagerConfig, templates)
 	if err != nil {
 		tflog.Error(ctx, "Failed to update Alertmanager config via POST", map[string]interface{}{"error": err})
-		resp.Diagnostics.AddError(
-			"Error updating Alertmanager config",
-			fmt.Sprintf("Failed to update Alertmanager config via POST: %s", err),
-		)
+		addAPIErrorDiagnostic(&resp.Diagnostics, "update the Alertmanager config", err)
 		return
 	}
 
@@ -166,19 +254,57 @@ func (r *AlertmanagerResource) Update(ctx context.Context, req resource.UpdateRe
 	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
 }
 
-func (r *AlertmanagerResource) Delete(ctx context.Context, _ resource.DeleteRequest, resp *resource.DeleteResponse) {
-	err := r.client.DeleteAlermanagerConfig(ctx)
-	if err != nil {
-		tflog.Error(ctx, "Failed to delete Alertmanager config", map[string]interface{}{"error": err})
-		resp.Diagnostics.AddError(
-			"Error deleting Alertmanager config",
-			fmt.Sprintf("Failed to delete Alertmanager config: %s", err),
-		)
+func (r *AlertmanagerResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state AlertmanagerResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	deleteTimeout, diags := state.Timeouts.Delete(ctx, defaultTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
 		return
 	}
+	ctx, cancel := context.WithTimeout(ctx, deleteTimeout)
+	defer cancel()
+
+	switch state.DestroyBehavior.ValueString() {
+	case destroyBehaviorKeep:
+		tflog.Info(ctx, "Keeping Alertmanager config in backend; only removing from state")
+	case destroyBehaviorRestore:
+		config, templates := state.RestoreConfigYAML, state.RestoreTemplatesConfigYAML
+		if config.IsNull() {
+			config, templates = state.PreviousConfigYAML, state.PreviousTemplatesConfigYAML
+		}
+		if config.IsNull() {
+			resp.Diagnostics.AddError(
+				"No Alertmanager Config to Restore",
+				"destroy_behavior is \"restore\" but restore_config_yaml is not set and no Alertmanager config existed when the resource was created. "+
+					"Set restore_config_yaml, or set destroy_behavior to \"delete\" or \"keep\" and apply before destroying.",
+			)
+			return
+		}
+		err := r.client.CreateAlertmanagerConfig(ctx, config.ValueString(), mapStringFromTypesMap(templates))
+		if err != nil {
+			tflog.Error(ctx, "Failed to restore Alertmanager config", map[string]interface{}{"error": err})
+			addAPIErrorDiagnostic(&resp.Diagnostics, "restore the previous Alertmanager config", err)
+			return
+		}
+	default:
+		err := r.client.DeleteAlermanagerConfig(ctx)
+		if err != nil {
+			tflog.Error(ctx, "Failed to delete Alertmanager config", map[string]interface{}{"error": err})
+			addAPIErrorDiagnostic(&resp.Diagnostics, "delete the Alertmanager config", err)
+			return
+		}
+	}
 	resp.State.RemoveResource(ctx)
 }
 
 func (r *AlertmanagerResource) ImportState(ctx context.Context, _ resource.ImportStateRequest, resp *resource.ImportStateResponse) {
 	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), "alertmanager")...)
+	// The config found in Grafana Mimir was not there before the resource, it is not restored on destroy
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("previous_config_yaml"), types.StringNull())...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("previous_templates_config_yaml"), types.MapNull(types.StringType))...)
 }
@@ -1,10 +1,17 @@
 package provider
 
 import (
+	"context"
+	"fmt"
 	"regexp"
 	"testing"
 
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
 )
 
 func TestAccResourceAlertmanager(t *testing.T) {
@@ -47,11 +54,102 @@ func TestAccResourceAlertmanagerImport(t *testing.T) {
 				ResourceName:      "mimirtool_alertmanager.demo",
 				ImportState:       true,
 				ImportStateVerify: true,
+				// These fields can't be retrieved from the Alertmanager API
+				ImportStateVerifyIgnore: []string{"destroy_behavior", "previous_config_yaml", "previous_templates_config_yaml"},
 			},
 		},
 	})
 }
 
+func TestAccResourceAlertmanagerDestroyKeep(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy: func(_ *terraform.State) error {
+			cli := testAccMimirClient(t)
+			config, _, err := cli.GetAlertmanagerConfig(context.Background())
+			if err != nil {
+				return fmt.Errorf("expected the Alertmanager config to be kept: %w", err)
+			}
+			if config != testAccResourceAlertmanagerYaml {
+				return fmt.Errorf("unexpected Alertmanager config after destroy: %s", config)
+			}
+			return cli.DeleteAlermanagerConfig(context.Background())
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceAlertmanagerDestroyKeep,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("mimirtool_alertmanager.demo", "destroy_behavior", "keep"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceAlertmanagerDestroyRestore(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		PreCheck: func() {
+			if err := testAccMimirClient(t).CreateAlertmanagerConfig(context.Background(), testAccResourceAlertmanagerPreviousYaml, nil); err != nil {
+				t.Fatalf("failed to create Alertmanager config: %s", err)
+			}
+		},
+		CheckDestroy: func(_ *terraform.State) error {
+			cli := testAccMimirClient(t)
+			config, _, err := cli.GetAlertmanagerConfig(context.Background())
+			if err != nil {
+				return fmt.Errorf("expected the previous Alertmanager config to be restored: %w", err)
+			}
+			if config != testAccResourceAlertmanagerPreviousYaml {
+				return fmt.Errorf("unexpected Alertmanager config after destroy: %s", config)
+			}
+			return cli.DeleteAlermanagerConfig(context.Background())
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceAlertmanagerDestroyRestore,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("mimirtool_alertmanager.demo", "previous_config_yaml", testAccResourceAlertmanagerPreviousYaml),
+				),
+			},
+		},
+	})
+}
+
+const testAccResourceAlertmanagerDestroyKeep = `
+provider "mimirtool" {
+  address = "http://localhost:8080"
+}
+
+resource "mimirtool_alertmanager" "demo" {
+	config_yaml = file("testdata/example_alertmanager_config.yaml")
+	templates_config_yaml = {
+	  default_template = file("testdata/example_alertmanager_template.tmpl")
+	}
+	destroy_behavior = "keep"
+}
+`
+
+const testAccResourceAlertmanagerDestroyRestore = `
+provider "mimirtool" {
+  address = "http://localhost:8080"
+}
+
+resource "mimirtool_alertmanager" "demo" {
+	config_yaml = file("testdata/example_alertmanager_config.yaml")
+	templates_config_yaml = {
+	  default_template = file("testdata/example_alertmanager_template.tmpl")
+	}
+	destroy_behavior = "restore"
+}
+`
+
+const testAccResourceAlertmanagerPreviousYaml = `route:
+  receiver: previous
+receivers:
+  - name: previous
+`
+
 const testAccResourceAlertmanager = `
 provider "mimirtool" {
   address = "http://localhost:8080"
@@ -94,3 +192,61 @@ foo: bar: baz
 YAML
 }
 `
+
+// fakeAlertmanagerClient stores the last Alertmanager config written
+type fakeAlertmanagerClient struct {
+	mimirClientInterface
+	config string
+}
+
+func (c *fakeAlertmanagerClient) CreateAlertmanagerConfig(_ context.Context, config string, _ map[string]string) error {
+	c.config = config
+	return nil
+}
+
+func TestAlertmanagerUpdateWithoutPreviousConfig(t *testing.T) {
+	ctx := context.Background()
+	cli := &fakeAlertmanagerClient{}
+	r := &AlertmanagerResource{client: cli}
+
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+	newState := func(model AlertmanagerResourceModel) tfsdk.State {
+		state := tfsdk.State{Schema: schemaResp.Schema, Raw: tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil)}
+		if diags := state.Set(ctx, &model); diags.HasError() {
+			t.Fatalf("unexpected diagnostics: %v", diags)
+		}
+		return state
+	}
+
+	// State imported, or written before previous_config_yaml existed
+	model := AlertmanagerResourceModel{
+		ID:                          types.StringValue("alertmanager"),
+		ConfigYAML:                  types.StringValue("route:\n  receiver: a\n"),
+		TemplatesConfigYAML:         types.MapNull(types.StringType),
+		DestroyBehavior:             types.StringValue(destroyBehaviorDelete),
+		RestoreConfigYAML:           types.StringNull(),
+		RestoreTemplatesConfigYAML:  types.MapNull(types.StringType),
+		PreviousConfigYAML:          types.StringNull(),
+		PreviousTemplatesConfigYAML: types.MapNull(types.StringType),
+		Timeouts:                    nullTimeouts(),
+	}
+	state := newState(model)
+	// UseStateForUnknown doesn't apply to null state values
+	model.ConfigYAML = types.StringValue("route:\n  receiver: b\n")
+	model.PreviousConfigYAML = types.StringUnknown()
+	model.PreviousTemplatesConfigYAML = types.MapUnknown(types.StringType)
+	plan := newState(model)
+
+	resp := &fwresource.UpdateResponse{State: newState(model)}
+	r.Update(ctx, fwresource.UpdateRequest{Plan: tfsdk.Plan(plan), State: state}, resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", resp.Diagnostics)
+	}
+	if !resp.State.Raw.IsFullyKnown() {
+		t.Fatalf("expected a fully known state after update, got %s", resp.State.Raw)
+	}
+	if cli.config != "route:\n  receiver: b\n" {
+		t.Fatalf("expected the planned config to be written, got %q", cli.config)
+	}
+}
@@ -3,11 +3,37 @@ package provider
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"time"
 
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
 	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
+// defaultTimeout is applied to resource operations when no timeouts block is configured.
+const defaultTimeout = 5 * time.Minute
+
+// timeoutsOpts lists the operations that can be given a timeout in the resources' timeouts block.
+var timeoutsOpts = timeouts.Opts{
+	Create: true,
+	Read:   true,
+	Update: true,
+	Delete: true,
+}
+
+// nullTimeouts returns an empty timeouts block, used when the state is built
+// from scratch (e.g. on import) and no configuration is available.
+func nullTimeouts() timeouts.Value {
+	return timeouts.Value{
+		Object: types.ObjectNull(map[string]attr.Type{
+			"create": types.StringType,
+			"read":   types.StringType,
+			"update": types.StringType,
+			"delete": types.StringType,
+		}),
+	}
+}
+
 func hash(s string) string {
 	sha := sha256.Sum256([]byte(s))
 	return hex.EncodeToString(sha[:])
@@ -0,0 +1,73 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// inlineSecretReader lets the dskit TLS configuration load PEM material given
+// inline in the provider configuration. Inline values are registered under a
+// placeholder path, every other path is read from disk.
+type inlineSecretReader map[string][]byte
+
+// add registers the inline PEM value if any and returns the path to give to
+// the TLS configuration.
+func (r inlineSecretReader) add(path, name, pem string) string {
+	if pem == "" {
+		return path
+	}
+	placeholder := "inline:" + name
+	r[placeholder] = []byte(pem)
+	return placeholder
+}
+
+func (r inlineSecretReader) ReadSecret(path string) ([]byte, error) {
+	if content, ok := r[path]; ok {
+		return content, nil
+	}
+	return os.ReadFile(path) // #nosec G304 -- path comes from the provider configuration
+}
+
+// credentialsFileTransport sets the Authorization header from credentials
+// stored in files. The files are read for every request so credentials
+// rotated on disk are picked up without restarting the provider.
+type credentialsFileTransport struct {
+	base          http.RoundTripper
+	authTokenFile string
+	apiKeyFile    string
+	basicAuthUser string
+}
+
+func (t *credentialsFileTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	switch {
+	case t.authTokenFile != "":
+		token, err := readCredentialFile(t.authTokenFile)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	case t.apiKeyFile != "":
+		key, err := readCredentialFile(t.apiKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		req.SetBasicAuth(t.basicAuthUser, key)
+	}
+
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req)
+}
+
+func readCredentialFile(path string) (string, error) {
+	content, err := os.ReadFile(path) // #nosec G304 -- path comes from the provider configuration
+	if err != nil {
+		return "", fmt.Errorf("failed to read credentials file: %w", err)
+	}
+	return strings.TrimSpace(string(content)), nil
+}
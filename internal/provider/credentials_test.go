@@ -0,0 +1,50 @@
+package provider
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCredentialsFileTransportRereadsFile(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	client := http.Client{Transport: &credentialsFileTransport{authTokenFile: tokenFile}}
+
+	for _, token := range []string{"first\n", "second"} {
+		if err := os.WriteFile(tokenFile, []byte(token), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	if len(got) != 2 || got[0] != "Bearer first" || got[1] != "Bearer second" {
+		t.Fatalf("unexpected Authorization headers: %q", got)
+	}
+}
+
+func TestInlineSecretReader(t *testing.T) {
+	reader := inlineSecretReader{}
+	if path := reader.add("/etc/ca.pem", "tls_ca_pem", ""); path != "/etc/ca.pem" {
+		t.Fatalf("expected file path to be kept, got %q", path)
+	}
+	path := reader.add("", "tls_cert_pem", "-----BEGIN CERTIFICATE-----")
+	content, err := reader.ReadSecret(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "-----BEGIN CERTIFICATE-----" {
+		t.Fatalf("unexpected content %q", content)
+	}
+}
@@ -0,0 +1,127 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/mimir/pkg/mimirtool/client"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+// The mimirtool client only exposes a sentinel error for 404, other statuses
+// are reported in the error message.
+var (
+	httpStatusErrorRegexp = regexp.MustCompile(`server returned HTTP status: (\d{3})[^,]*(?:, body: ("(?:[^"\\]|\\.)*"))?`)
+	conflictErrorRegexp   = regexp.MustCompile(`conflict with current state of target resource`)
+	tooManyRequestsRegexp = regexp.MustCompile(`too many requests`)
+)
+
+// apiError is an error returned by the Grafana Mimir API, classified by HTTP status.
+type apiError struct {
+	// StatusCode is 0 when the error doesn't come from an HTTP answer (e.g. network errors)
+	StatusCode int
+	// Message is the body returned by Grafana Mimir, if any
+	Message string
+	Err     error
+}
+
+func (e *apiError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *apiError) Unwrap() error {
+	return e.Err
+}
+
+// parseAPIError extracts the HTTP status and message from an error returned by the mimirtool client.
+func parseAPIError(err error) *apiError {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	apiErr = &apiError{Err: err}
+	switch {
+	case errors.Is(err, client.ErrResourceNotFound):
+		apiErr.StatusCode = http.StatusNotFound
+	case conflictErrorRegexp.MatchString(err.Error()):
+		apiErr.StatusCode = http.StatusConflict
+	case tooManyRequestsRegexp.MatchString(err.Error()):
+		apiErr.StatusCode = http.StatusTooManyRequests
+	default:
+		matches := httpStatusErrorRegexp.FindStringSubmatch(err.Error())
+		if matches == nil {
+			return apiErr
+		}
+		apiErr.StatusCode, _ = strconv.Atoi(matches[1])
+		if matches[2] != "" {
+			if message, err := strconv.Unquote(matches[2]); err == nil {
+				apiErr.Message = message
+			}
+		}
+	}
+	return apiErr
+}
+
+// isNotFound reports whether err is a 404 answer from Grafana Mimir.
+func isNotFound(err error) bool {
+	return err != nil && parseAPIError(err).StatusCode == http.StatusNotFound
+}
+
+// addAPIErrorDiagnostic reports an error returned by the Grafana Mimir API with
+// a summary and hints specific to its HTTP status. action describes what the
+// provider was doing, e.g. "create rule group \"foo\"".
+func addAPIErrorDiagnostic(diagnostics *diag.Diagnostics, action string, err error) {
+	summary, detail := apiErrorDiagnostic(action, err)
+	diagnostics.AddError(summary, detail)
+}
+
+func apiErrorDiagnostic(action string, err error) (string, string) {
+	apiErr := parseAPIError(err)
+	message := apiErr.Error()
+	if apiErr.Message != "" {
+		// Keep the context of the wrapping errors, e.g. the rule group, before the body
+		message = apiErr.Message
+		if loc := httpStatusErrorRegexp.FindStringIndex(err.Error()); loc != nil {
+			if prefix := strings.TrimRight(err.Error()[:loc[0]], ": "); prefix != "" {
+				message = prefix + ":\n\n" + message
+			}
+		}
+	}
+
+	switch code := apiErr.StatusCode; {
+	case code == http.StatusBadRequest:
+		return "Invalid Request Rejected by Grafana Mimir",
+			fmt.Sprintf("Grafana Mimir refused to %s as the request is invalid:\n\n%s", action, message)
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		return "Access Denied by Grafana Mimir",
+			fmt.Sprintf("Grafana Mimir denied the permission to %s (HTTP %d). "+
+				"Check the provider \"tenant_id\" and that the credentials (\"api_user\"/\"api_key\" or \"auth_token\") are valid and allowed to access this tenant.\n\n%s", action, code, message)
+	case code == http.StatusNotFound:
+		return "Resource Not Found in Grafana Mimir",
+			fmt.Sprintf("Grafana Mimir could not find the resource while trying to %s. "+
+				"Check that the corresponding API is enabled and that the provider \"address\" and HTTP prefixes are correct.\n\n%s", action, message)
+	case code == http.StatusConflict:
+		return "Conflict Reported by Grafana Mimir",
+			fmt.Sprintf("Grafana Mimir reported a conflict while trying to %s. Another change may be in progress, please retry.\n\n%s", action, message)
+	case code == http.StatusRequestEntityTooLarge:
+		return "Payload Too Large for Grafana Mimir",
+			fmt.Sprintf("Grafana Mimir refused to %s as the payload exceeds its size limit. "+
+				"Split the rules into smaller groups or raise the limit on the Grafana Mimir side.\n\n%s", action, message)
+	case code == http.StatusTooManyRequests:
+		return "Rate Limited by Grafana Mimir",
+			fmt.Sprintf("Grafana Mimir rate limited the request to %s. "+
+				"Retry later, reduce Terraform parallelism (-parallelism) or check the tenant limits.\n\n%s", action, message)
+	case code >= 500:
+		return "Grafana Mimir Server Error",
+			fmt.Sprintf("Grafana Mimir failed with HTTP %d while trying to %s. This is usually transient, please retry the operation.\n\n%s", code, action, message)
+	default:
+		return "Unexpected Grafana Mimir Error",
+			fmt.Sprintf("An unexpected error occurred while trying to %s. "+
+				"Please retry the operation or report this issue to the provider developers.\n\n%s", action, message)
+	}
+}
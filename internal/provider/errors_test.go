@@ -0,0 +1,77 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/grafana/mimir/pkg/mimirtool/client"
+	pkgerrors "github.com/pkg/errors"
+)
+
+func TestAPIErrorDiagnostic(t *testing.T) {
+	for _, tc := range []struct {
+		err             error
+		expectedStatus  int
+		expectedSummary string
+		expectedDetail  string
+	}{
+		{
+			err:             pkgerrors.Wrap(errors.New(`server returned HTTP status: 400 Bad Request, body: "invalid rules config: group 'foo' has no rules"`), "POST request to http://localhost/prometheus/config/v1/rules/demo failed"),
+			expectedStatus:  400,
+			expectedSummary: "Invalid Request Rejected by Grafana Mimir",
+			expectedDetail:  "group 'foo' has no rules",
+		},
+		{
+			// The rule group rejected is kept with the body
+			err: &ruleGroupError{Action: "create", Group: "bar", Err: pkgerrors.Wrap(errors.New(`server returned HTTP status: 400 Bad Request, body: "invalid rules config: group 'bar' has no rules"`),
+				"POST request to http://localhost/prometheus/config/v1/rules/demo failed")},
+			expectedStatus:  400,
+			expectedSummary: "Invalid Request Rejected by Grafana Mimir",
+			expectedDetail:  "failed to create rule group \"bar\": POST request to http://localhost/prometheus/config/v1/rules/demo failed:\n\ninvalid rules config: group 'bar' has no rules",
+		},
+		{
+			err:             errors.New(`server returned HTTP status: 401 Unauthorized`),
+			expectedStatus:  401,
+			expectedSummary: "Access Denied by Grafana Mimir",
+			expectedDetail:  "tenant_id",
+		},
+		{
+			err:             fmt.Errorf("rule group %q: %w", "foo", pkgerrors.Wrap(client.ErrResourceNotFound, "GET request failed")),
+			expectedStatus:  404,
+			expectedSummary: "Resource Not Found in Grafana Mimir",
+		},
+		{
+			err:             errors.New(`server returned HTTP status: 413 Request Entity Too Large, body: "request too large"`),
+			expectedStatus:  413,
+			expectedSummary: "Payload Too Large for Grafana Mimir",
+		},
+		{
+			err:             errors.New("POST request failed: too many requests"),
+			expectedStatus:  429,
+			expectedSummary: "Rate Limited by Grafana Mimir",
+		},
+		{
+			err:             errors.New(`server returned HTTP status: 503 Service Unavailable`),
+			expectedStatus:  503,
+			expectedSummary: "Grafana Mimir Server Error",
+		},
+		{
+			err:             errors.New("dial tcp: connection refused"),
+			expectedSummary: "Unexpected Grafana Mimir Error",
+			expectedDetail:  "connection refused",
+		},
+	} {
+		if status := parseAPIError(tc.err).StatusCode; status != tc.expectedStatus {
+			t.Errorf("%q: expected status %d, got %d", tc.err, tc.expectedStatus, status)
+		}
+		summary, detail := apiErrorDiagnostic("create rule group", tc.err)
+		if summary != tc.expectedSummary {
+			t.Errorf("%q: expected summary %q, got %q", tc.err, tc.expectedSummary, summary)
+		}
+		if !strings.Contains(detail, tc.expectedDetail) {
+			t.Errorf("%q: expected detail to contain %q, got %q", tc.err, tc.expectedDetail, detail)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package provider
+
+import (
+	"fmt"
+
+	"github.com/grafana/mimir/pkg/mimirtool/rules"
+	"github.com/grafana/mimir/pkg/mimirtool/rules/rwrulefmt"
+	goversion "github.com/hashicorp/go-version"
+)
+
+// How rule group fields unsupported by the probed version are reported
+const (
+	unsupportedRuleGroupFieldsWarn = "warn"
+	unsupportedRuleGroupFieldsFail = "fail"
+)
+
+// ruleGroupField describes a rule group or rule field that is only understood
+// by recent Grafana Mimir versions. Older versions silently drop it.
+type ruleGroupField struct {
+	name       string
+	minVersion *goversion.Version
+	used       func(rwrulefmt.RuleGroup) bool
+}
+
+var ruleGroupFields = []ruleGroupField{
+	{
+		name:       "evaluation_delay",
+		minVersion: goversion.Must(goversion.NewVersion("2.1.0")),
+		used:       func(g rwrulefmt.RuleGroup) bool { return g.EvaluationDelay != nil },
+	},
+	{
+		name:       "keep_firing_for",
+		minVersion: goversion.Must(goversion.NewVersion("2.7.0")),
+		used: func(g rwrulefmt.RuleGroup) bool {
+			for _, rule := range g.Rules {
+				if rule.KeepFiringFor != 0 {
+					return true
+				}
+			}
+			return false
+		},
+	},
+	{
+		name:       "align_evaluation_time_on_interval",
+		minVersion: goversion.Must(goversion.NewVersion("2.10.0")),
+		used:       func(g rwrulefmt.RuleGroup) bool { return g.AlignEvaluationTimeOnInterval },
+	},
+	{
+		name:       "query_offset",
+		minVersion: goversion.Must(goversion.NewVersion("2.13.0")),
+		used:       func(g rwrulefmt.RuleGroup) bool { return g.QueryOffset != nil },
+	},
+}
+
+// evaluationDelayDeprecation is the version deprecating evaluation_delay in favor of query_offset
+var evaluationDelayDeprecation = goversion.Must(goversion.NewVersion("2.13.0"))
+
+// checkDeprecatedRuleGroupFields returns a warning for each rule group using
+// evaluation_delay when the Grafana Mimir version supports query_offset, or
+// when the version is unknown.
+func checkDeprecatedRuleGroupFields(ruleNamespace rules.RuleNamespace, mimirVersion *goversion.Version) []string {
+	if mimirVersion != nil && mimirVersion.Core().LessThan(evaluationDelayDeprecation) {
+		return nil
+	}
+	var warnings []string
+	for _, group := range ruleNamespace.Groups {
+		if group.EvaluationDelay != nil && group.QueryOffset == nil {
+			warnings = append(warnings, fmt.Sprintf("group %q uses \"evaluation_delay\" which is deprecated since Grafana Mimir %s, "+
+				"use \"query_offset\" instead, it has the same meaning", group.Name, evaluationDelayDeprecation))
+		}
+	}
+	return warnings
+}
+
+// checkRuleGroupFieldsSupport returns an error for each rule group using a
+// field the given Grafana Mimir version doesn't support. Nothing is checked
+// when the version is unknown.
+func checkRuleGroupFieldsSupport(ruleNamespace rules.RuleNamespace, mimirVersion *goversion.Version) []error {
+	if mimirVersion == nil {
+		return nil
+	}
+	var errs []error
+	for _, group := range ruleNamespace.Groups {
+		for _, field := range ruleGroupFields {
+			if field.used(group) && mimirVersion.Core().LessThan(field.minVersion) {
+				errs = append(errs, fmt.Errorf("group %q uses %q which requires Grafana Mimir %s or later, connected Grafana Mimir is %s and would silently drop it",
+					group.Name, field.name, field.minVersion, mimirVersion))
+			}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,235 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/google/go-jsonnet"
+	"github.com/grafana/mimir/pkg/mimirtool/rules"
+	"github.com/grafana/mimir/pkg/mimirtool/rules/rwrulefmt"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"gopkg.in/yaml.v3"
+)
+
+// mixinEntrypoint is the file evaluated when the source of a mixin is a directory
+const mixinEntrypoint = "mixin.libsonnet"
+
+// mixinRulesSnippet extracts the rules and alerts of a mixin, they are
+// usually hidden fields (prometheusAlerts+::) so they are read explicitly.
+const mixinRulesSnippet = `local mixin = import %s;
+{
+  rules: if std.objectHasAll(mixin, 'prometheusRules') then mixin.prometheusRules else { groups: [] },
+  alerts: if std.objectHasAll(mixin, 'prometheusAlerts') then mixin.prometheusAlerts else { groups: [] },
+}
+`
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &MixinRulesDataSource{}
+
+func NewMixinRulesDataSource() datasource.DataSource {
+	return &MixinRulesDataSource{}
+}
+
+// MixinRulesDataSource renders the rules and alerts of a Jsonnet monitoring
+// mixin. It only reads local files, so that vendored mixins render offline.
+type MixinRulesDataSource struct{}
+
+// MixinRulesDataSourceModel describes the data source data model.
+type MixinRulesDataSourceModel struct {
+	ID         types.String `tfsdk:"id"`
+	Source     types.String `tfsdk:"source"`
+	JPath      types.List   `tfsdk:"jpath"`
+	ExtVars    types.Map    `tfsdk:"ext_vars"`
+	RulesYAML  types.String `tfsdk:"rules_yaml"`
+	AlertsYAML types.String `tfsdk:"alerts_yaml"`
+	ConfigYAML types.String `tfsdk:"config_yaml"`
+}
+
+func (d *MixinRulesDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_mixin_rules"
+}
+
+func (d *MixinRulesDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Renders the `prometheusRules` and `prometheusAlerts` of a Jsonnet [monitoring mixin](https://monitoring.mixins.dev/) as namespace YAML " +
+			"for the `mimirtool_ruler_namespace` resource. Only local files are read, vendor the mixin and its dependencies (e.g. with jsonnet-bundler) to render it without network access.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				MarkdownDescription: "hash",
+				Computed:            true,
+			},
+			"source": schema.StringAttribute{
+				MarkdownDescription: "Path of the Jsonnet file evaluating to the mixin, or of the directory of the mixin where `" + mixinEntrypoint + "` is evaluated.",
+				Required:            true,
+			},
+			"jpath": schema.ListAttribute{
+				MarkdownDescription: "Library search paths of the Jsonnet imports, the first match wins. Defaults to the `vendor` directory next to the evaluated file when it exists.",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"ext_vars": schema.MapAttribute{
+				MarkdownDescription: "External string variables of the mixin, read with `std.extVar()`.",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"rules_yaml": schema.StringAttribute{
+				MarkdownDescription: "The recording rule groups of the mixin (`prometheusRules`) as namespace YAML.",
+				Computed:            true,
+			},
+			"alerts_yaml": schema.StringAttribute{
+				MarkdownDescription: "The alerting rule groups of the mixin (`prometheusAlerts`) as namespace YAML.",
+				Computed:            true,
+			},
+			"config_yaml": schema.StringAttribute{
+				MarkdownDescription: "The recording and alerting rule groups of the mixin as namespace YAML, for the `config_yaml` of a `mimirtool_ruler_namespace` resource.",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (d *MixinRulesDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	tflog.Debug(ctx, "READ - init")
+	var data MixinRulesDataSourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var jpath []string
+	if !data.JPath.IsNull() {
+		resp.Diagnostics.Append(data.JPath.ElementsAs(ctx, &jpath, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	rendered, err := renderMixinRules(ctx, data.Source.ValueString(), jpath, stringMapValue(data.ExtVars))
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("source"), "Unable to Render Mixin", err.Error())
+		return
+	}
+
+	data.RulesYAML = types.StringValue(rendered.rules)
+	data.AlertsYAML = types.StringValue(rendered.alerts)
+	data.ConfigYAML = types.StringValue(rendered.config)
+	data.ID = types.StringValue(hash(rendered.config))
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// mixinRules are the rule groups of a mixin as namespace YAML
+type mixinRules struct {
+	rules  string
+	alerts string
+	// config merges the recording and alerting rule groups
+	config string
+}
+
+// renderMixinRules evaluates the mixin and returns its rules and alerts as
+// validated namespace YAML.
+func renderMixinRules(ctx context.Context, source string, jpath []string, extVars map[string]string) (mixinRules, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return mixinRules{}, err
+	}
+	entrypoint := source
+	if info.IsDir() {
+		entrypoint = filepath.Join(source, mixinEntrypoint)
+	}
+	entrypoint, err = filepath.Abs(entrypoint)
+	if err != nil {
+		return mixinRules{}, err
+	}
+	if jpath == nil {
+		// jsonnet-bundler layout
+		if vendor := filepath.Join(filepath.Dir(entrypoint), "vendor"); isDir(vendor) {
+			jpath = []string{vendor}
+		}
+	}
+
+	vm := jsonnet.MakeVM()
+	vm.Importer(&jsonnet.FileImporter{JPaths: jpath})
+	for name, value := range extVars {
+		vm.ExtVar(name, value)
+	}
+	// A JSON string is a valid Jsonnet string literal
+	importPath, err := json.Marshal(entrypoint)
+	if err != nil {
+		return mixinRules{}, err
+	}
+	out, err := vm.EvaluateAnonymousSnippet(filepath.Join(filepath.Dir(entrypoint), "<mixin>"), fmt.Sprintf(mixinRulesSnippet, importPath))
+	if err != nil {
+		return mixinRules{}, err
+	}
+
+	var evaluated struct {
+		Rules  any `json:"rules"`
+		Alerts any `json:"alerts"`
+	}
+	if err := json.Unmarshal([]byte(out), &evaluated); err != nil {
+		return mixinRules{}, err
+	}
+	recording, err := mixinRuleNamespace(ctx, "prometheusRules", evaluated.Rules)
+	if err != nil {
+		return mixinRules{}, err
+	}
+	alerting, err := mixinRuleNamespace(ctx, "prometheusAlerts", evaluated.Alerts)
+	if err != nil {
+		return mixinRules{}, err
+	}
+	if len(recording.Groups)+len(alerting.Groups) == 0 {
+		return mixinRules{}, fmt.Errorf("the mixin evaluated from %s defines no rule group in prometheusRules nor prometheusAlerts", entrypoint)
+	}
+
+	// Both sets of groups end up in the same namespace
+	names := ruleGroupNames(recording.Groups)
+	for _, group := range alerting.Groups {
+		if names[group.Name] {
+			return mixinRules{}, fmt.Errorf("group %q is defined in both prometheusRules and prometheusAlerts", group.Name)
+		}
+	}
+	merged := rules.RuleNamespace{Groups: append(append([]rwrulefmt.RuleGroup{}, recording.Groups...), alerting.Groups...)}
+
+	var rendered mixinRules
+	for _, output := range []struct {
+		ns  rules.RuleNamespace
+		out *string
+	}{
+		{recording, &rendered.rules},
+		{alerting, &rendered.alerts},
+		{merged, &rendered.config},
+	} {
+		content, err := yaml.Marshal(output.ns)
+		if err != nil {
+			return mixinRules{}, err
+		}
+		*output.out = string(content)
+	}
+	return rendered, nil
+}
+
+// mixinRuleNamespace validates the rule groups of a mixin field
+func mixinRuleNamespace(ctx context.Context, field string, value any) (rules.RuleNamespace, error) {
+	content, err := yaml.Marshal(value)
+	if err != nil {
+		return rules.RuleNamespace{}, err
+	}
+	ruleNamespace, err := getRuleNamespaceFromYAML(ctx, string(content), rulerBackendMimir)
+	if err != nil {
+		return rules.RuleNamespace{}, fmt.Errorf("%s: %w", field, err)
+	}
+	return ruleNamespace, nil
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
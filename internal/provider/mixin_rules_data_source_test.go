@@ -0,0 +1,84 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/statecheck"
+)
+
+func TestRenderMixinRules(t *testing.T) {
+	ctx := context.Background()
+	rendered, err := renderMixinRules(ctx, "testdata/mixin", nil, map[string]string{"job": "api"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ruleNamespace, err := getRuleNamespaceFromYAML(ctx, rendered.config, rulerBackendMimir)
+	if err != nil {
+		t.Fatalf("expected valid namespace YAML, got %s:\n%s", err, rendered.config)
+	}
+	if len(ruleNamespace.Groups) != 2 || ruleNamespace.Groups[0].Name != "example_rules" || ruleNamespace.Groups[1].Name != "example_alerts" {
+		t.Fatalf("unexpected groups:\n%s", rendered.config)
+	}
+	if expr := ruleNamespace.Groups[1].Rules[0].Expr.Value; expr != `job:up:sum{job="api"} == 0` {
+		t.Fatalf("unexpected expression %q", expr)
+	}
+	if strings.Contains(rendered.rules, "example_alerts") || strings.Contains(rendered.alerts, "example_rules") {
+		t.Fatalf("unexpected split of the rules:\n%s\n%s", rendered.rules, rendered.alerts)
+	}
+
+	// The vendored library is only found in the vendor directory
+	if _, err := renderMixinRules(ctx, "testdata/mixin/mixin.libsonnet", []string{"testdata"}, map[string]string{"job": "api"}); err == nil ||
+		!strings.Contains(err.Error(), "couldn't open import") {
+		t.Fatalf("expected the import to fail without the vendor directory, got %v", err)
+	}
+	if _, err := renderMixinRules(ctx, "testdata/mixin", nil, nil); err == nil || !strings.Contains(err.Error(), "Undefined external variable: job") {
+		t.Fatalf("expected the missing external variable to be reported, got %v", err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "mixin.libsonnet"), []byte(`{ prometheusAlerts:: { groups: [{ name: 'g', rules: [{ alert: 'A', expr: 'sum(' }] }] } }`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := renderMixinRules(ctx, dir, nil, nil); err == nil || !strings.Contains(err.Error(), "prometheusAlerts:") {
+		t.Fatalf("expected the invalid expression to be reported, got %v", err)
+	}
+}
+
+func TestAccDataSourceMixinRules(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataSourceMixinRules,
+				ConfigStateChecks: []statecheck.StateCheck{
+					SemanticYAMLStateCheck("data.mimirtool_mixin_rules.example", "rules_yaml", testAccDataSourceMixinRulesYaml),
+				},
+			},
+		},
+	})
+}
+
+const testAccDataSourceMixinRules = `
+provider "mimirtool" {
+  address = "http://localhost:8080"
+}
+
+data "mimirtool_mixin_rules" "example" {
+  source   = "testdata/mixin"
+  ext_vars = {
+    job = "api"
+  }
+}
+`
+
+const testAccDataSourceMixinRulesYaml = `groups:
+- name: example_rules
+  rules:
+  - record: job:up:sum
+    expr: sum by (job) (up{job="api"})
+`
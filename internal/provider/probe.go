@@ -0,0 +1,138 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	goversion "github.com/hashicorp/go-version"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// mimirBuildInfo is the subset of the build-info endpoint response used by the provider.
+// See: https://grafana.com/docs/mimir/latest/references/http-api/#build-information
+type mimirBuildInfo struct {
+	Status   string            `json:"status"`
+	Version  string            `json:"version"`
+	Revision string            `json:"revision"`
+	Features map[string]string `json:"features"`
+}
+
+// probeMimir checks that Grafana Mimir is reachable with the configured
+// credentials and returns its build information. Any failure is reported as a
+// single provider diagnostic.
+func probeMimir(ctx context.Context, httpClient *http.Client, cfg MimirClientConfig, diagnostics *diag.Diagnostics) (*mimirBuildInfo, bool) {
+	buildInfoPath, err := url.JoinPath(cfg.PrometheusHTTPPrefix, "/api/v1/status/buildinfo")
+	if err != nil {
+		diagnostics.AddError("Invalid Provider Configuration", fmt.Sprintf("Invalid prometheus_http_prefix: %s", err))
+		return nil, false
+	}
+
+	body, status, err := probeRequest(ctx, httpClient, cfg, buildInfoPath)
+	switch {
+	case err != nil:
+		diagnostics.AddError(
+			"Unable to Reach Grafana Mimir",
+			fmt.Sprintf("The provider could not contact Grafana Mimir at %q: %s\n\n"+
+				"Check the \"address\" configuration and that the server is reachable from where Terraform runs.", cfg.Address, err),
+		)
+		return nil, false
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		diagnostics.AddError(
+			"Grafana Mimir Rejected the Provider Credentials",
+			fmt.Sprintf("Grafana Mimir answered HTTP %d to the startup probe. "+
+				"Check the \"tenant_id\", \"api_user\", \"api_key\" and \"auth_token\" configuration (or their file and environment variable alternatives).", status),
+		)
+		return nil, false
+	case status != http.StatusOK:
+		diagnostics.AddError(
+			"Unexpected Answer from Grafana Mimir",
+			fmt.Sprintf("Grafana Mimir answered HTTP %d on %s during the startup probe: %s\n\n"+
+				"Check the \"address\" and \"prometheus_http_prefix\" configuration.", status, buildInfoPath, body),
+		)
+		return nil, false
+	}
+
+	var info mimirBuildInfo
+	if err := json.Unmarshal(body, &info); err != nil {
+		diagnostics.AddError(
+			"Unexpected Answer from Grafana Mimir",
+			fmt.Sprintf("Could not decode the build information returned by %s: %s", buildInfoPath, err),
+		)
+		return nil, false
+	}
+
+	// Readiness is not fatal: the server answered and accepted the credentials
+	_, status, err = probeRequest(ctx, httpClient, cfg, "/ready")
+	if err != nil || status != http.StatusOK {
+		diagnostics.AddWarning(
+			"Grafana Mimir Is Not Ready",
+			fmt.Sprintf("The readiness endpoint of Grafana Mimir at %q did not report ready (status %d, error: %v). "+
+				"Operations may fail until it is.", cfg.Address, status, err),
+		)
+	}
+
+	tflog.Info(ctx, "Probed Grafana Mimir", map[string]interface{}{
+		"version":  info.Version,
+		"revision": info.Revision,
+		"features": info.Features,
+	})
+
+	return &info, true
+}
+
+func probeRequest(ctx context.Context, httpClient *http.Client, cfg MimirClientConfig, path string) ([]byte, int, error) {
+	endpoint, err := url.Parse(cfg.Address)
+	if err != nil {
+		return nil, 0, err
+	}
+	endpoint = endpoint.JoinPath(path)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Mirror the authentication done by the mimirtool client, credentials
+	// read from files are set by the client transport.
+	switch {
+	case cfg.APIUser != "":
+		req.SetBasicAuth(cfg.APIUser, cfg.APIKey)
+	case cfg.APIKey != "":
+		req.SetBasicAuth(cfg.TenantID, cfg.APIKey)
+	case cfg.AuthToken != "":
+		req.Header.Set("Authorization", "Bearer "+cfg.AuthToken)
+	}
+	req.Header.Set("X-Scope-OrgID", cfg.TenantID)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return nil, resp.StatusCode, err
+	}
+	return []byte(strings.TrimSpace(string(body))), resp.StatusCode, nil
+}
+
+// parseMimirVersion returns the version reported by Grafana Mimir, or nil when
+// it can't be interpreted (e.g. development builds).
+func parseMimirVersion(ctx context.Context, raw string) *goversion.Version {
+	v, err := goversion.NewVersion(raw)
+	if err != nil {
+		tflog.Warn(ctx, "Unable to parse the Grafana Mimir version, version specific validation is disabled", map[string]interface{}{
+			"version": raw,
+			"error":   err.Error(),
+		})
+		return nil
+	}
+	return v
+}
@@ -0,0 +1,130 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+func TestProbeMimir(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" || r.Header.Get("X-Scope-OrgID") != "demo" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/prometheus/api/v1/status/buildinfo":
+			_, _ = w.Write([]byte(`{"status":"success","version":"2.16.1","features":{"federated_rules":"true"}}`))
+		case "/ready":
+			_, _ = w.Write([]byte("ready"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	cfg := MimirClientConfig{Address: server.URL, TenantID: "demo", AuthToken: "secret", PrometheusHTTPPrefix: "/prometheus"}
+
+	var diags diag.Diagnostics
+	info, ok := probeMimir(context.Background(), server.Client(), cfg, &diags)
+	if !ok || diags.HasError() || diags.WarningsCount() > 0 {
+		t.Fatalf("unexpected probe failure: %v", diags)
+	}
+	if info.Version != "2.16.1" || info.Features["federated_rules"] != "true" {
+		t.Fatalf("unexpected build info: %+v", info)
+	}
+
+	cfg.AuthToken = "wrong"
+	diags = nil
+	if _, ok := probeMimir(context.Background(), server.Client(), cfg, &diags); ok {
+		t.Fatal("expected the probe to fail with wrong credentials")
+	}
+	if diags.ErrorsCount() != 1 || !strings.Contains(diags[0].Detail(), "tenant_id") {
+		t.Fatalf("expected a single credentials diagnostic, got: %v", diags)
+	}
+}
+
+func TestCheckRuleGroupFieldsSupport(t *testing.T) {
+	ruleNamespace, err := getRuleNamespaceFromYAML(context.Background(), `groups:
+- name: offset
+  query_offset: 1m
+  rules:
+  - record: job:up:sum
+    expr: sum by (job) (up)
+`, rulerBackendMimir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if errs := checkRuleGroupFieldsSupport(ruleNamespace, nil); len(errs) != 0 {
+		t.Fatalf("expected no check without a version, got %v", errs)
+	}
+	if errs := checkRuleGroupFieldsSupport(ruleNamespace, parseMimirVersion(context.Background(), "2.16.1")); len(errs) != 0 {
+		t.Fatalf("expected query_offset to be supported, got %v", errs)
+	}
+	errs := checkRuleGroupFieldsSupport(ruleNamespace, parseMimirVersion(context.Background(), "2.12.0"))
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "query_offset") {
+		t.Fatalf("expected query_offset to be rejected, got %v", errs)
+	}
+}
+
+func TestCheckDeprecatedRuleGroupFields(t *testing.T) {
+	ruleNamespace, err := getRuleNamespaceFromYAML(context.Background(), `groups:
+- name: delayed
+  evaluation_delay: 1m
+  rules:
+  - record: job:up:sum
+    expr: sum by (job) (up)
+`, rulerBackendMimir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if warnings := checkDeprecatedRuleGroupFields(ruleNamespace, parseMimirVersion(context.Background(), "2.12.0")); len(warnings) != 0 {
+		t.Fatalf("expected no deprecation before query_offset is supported, got %v", warnings)
+	}
+	for _, version := range []string{"", "2.13.0"} {
+		warnings := checkDeprecatedRuleGroupFields(ruleNamespace, parseMimirVersion(context.Background(), version))
+		if len(warnings) != 1 || !strings.Contains(warnings[0], "query_offset") {
+			t.Fatalf("version %q: expected evaluation_delay to be deprecated, got %v", version, warnings)
+		}
+	}
+	errs := checkRuleGroupFieldsSupport(ruleNamespace, parseMimirVersion(context.Background(), "2.0.0"))
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "evaluation_delay") {
+		t.Fatalf("expected evaluation_delay to be rejected, got %v", errs)
+	}
+}
+
+func TestCheckRuleGroupOptions(t *testing.T) {
+	ruleNamespace, err := getRuleNamespaceFromYAML(context.Background(), `groups:
+- name: both
+  evaluation_delay: 1m
+  query_offset: 1m
+  rules:
+  - record: job:up:sum
+    expr: sum by (job) (up)
+- name: negative
+  limit: -1
+  rules:
+  - record: job:up:count
+    expr: count by (job) (up)
+- name: valid
+  query_offset: 1m
+  limit: 10
+  rules:
+  - record: job:up:max
+    expr: max by (job) (up)
+`, rulerBackendMimir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errs := checkRuleGroupOptions(ruleNamespace)
+	if len(errs) != 2 || !strings.Contains(errs[0].Error(), `"both"`) || !strings.Contains(errs[1].Error(), `"negative"`) {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+}
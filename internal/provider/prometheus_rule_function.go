@@ -0,0 +1,157 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"gopkg.in/yaml.v3"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ function.Function = &FromPrometheusRuleFunction{}
+
+// prometheusRuleAttributeTypes are the attributes of the object returned by from_prometheus_rule
+var prometheusRuleAttributeTypes = map[string]attr.Type{
+	"namespace":   types.StringType,
+	"config_yaml": types.StringType,
+}
+
+// prometheusRuleIgnoredGroupFields are the PrometheusRule group fields only
+// understood by Thanos, dropped as Grafana Mimir rejects them.
+var prometheusRuleIgnoredGroupFields = []string{"partial_response_strategy"}
+
+func NewFromPrometheusRuleFunction() function.Function {
+	return &FromPrometheusRuleFunction{}
+}
+
+// FromPrometheusRuleFunction converts a Prometheus Operator PrometheusRule
+// manifest to the input of the mimirtool_ruler_namespace resource.
+type FromPrometheusRuleFunction struct{}
+
+func (f *FromPrometheusRuleFunction) Metadata(_ context.Context, _ function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "from_prometheus_rule"
+}
+
+func (f *FromPrometheusRuleFunction) Definition(_ context.Context, _ function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary: "Converts a PrometheusRule manifest to ruler namespace input",
+		MarkdownDescription: "Extracts the rule groups of a Prometheus Operator `PrometheusRule` manifest (`spec.groups`) as the `config_yaml` " +
+			"of a `mimirtool_ruler_namespace` resource, where they are checked like any other rule group. " +
+			"The Thanos specific `partial_response_strategy` group field is dropped. " +
+			"The returned `namespace` is derived from the manifest metadata as `<metadata.namespace>/<metadata.name>`, or `<metadata.name>` without a namespace.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:                "manifest",
+				MarkdownDescription: "The YAML of a `PrometheusRule` manifest (`monitoring.coreos.com/v1`), e.g. read with `file()`.",
+			},
+		},
+		Return: function.ObjectReturn{
+			AttributeTypes: prometheusRuleAttributeTypes,
+		},
+	}
+}
+
+func (f *FromPrometheusRuleFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var manifest string
+	resp.Error = function.ConcatFuncErrors(req.Arguments.Get(ctx, &manifest))
+	if resp.Error != nil {
+		return
+	}
+
+	namespace, configYAML, err := parsePrometheusRule(manifest)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, fmt.Sprintf("Invalid PrometheusRule manifest: %s", err))
+		return
+	}
+
+	result, diags := types.ObjectValue(prometheusRuleAttributeTypes, map[string]attr.Value{
+		"namespace":   types.StringValue(namespace),
+		"config_yaml": types.StringValue(configYAML),
+	})
+	resp.Error = function.FuncErrorFromDiags(ctx, diags)
+	if resp.Error != nil {
+		return
+	}
+	resp.Error = resp.Result.Set(ctx, result)
+}
+
+// prometheusRule is the subset of a PrometheusRule manifest used by the provider.
+// See: https://prometheus-operator.dev/docs/api-reference/api/#monitoring.coreos.com/v1.PrometheusRule
+type prometheusRule struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"metadata"`
+	Spec struct {
+		// The groups are kept as written, they are checked by the resource
+		Groups []yaml.Node `yaml:"groups"`
+	} `yaml:"spec"`
+}
+
+// parsePrometheusRule returns the namespace derived from the metadata of a
+// PrometheusRule manifest and its rule groups as namespace YAML.
+func parsePrometheusRule(manifest string) (string, string, error) {
+	decoder := yaml.NewDecoder(strings.NewReader(manifest))
+	var rule prometheusRule
+	if err := decoder.Decode(&rule); err != nil {
+		if errors.Is(err, io.EOF) {
+			return "", "", errors.New("the manifest is empty")
+		}
+		return "", "", err
+	}
+	var next yaml.Node
+	if err := decoder.Decode(&next); !errors.Is(err, io.EOF) {
+		return "", "", errors.New("the manifest must contain a single document")
+	}
+
+	if rule.Kind != "PrometheusRule" || !strings.HasPrefix(rule.APIVersion, "monitoring.coreos.com/") {
+		return "", "", fmt.Errorf("expected kind PrometheusRule of apiVersion monitoring.coreos.com/v1, got kind %q of apiVersion %q", rule.Kind, rule.APIVersion)
+	}
+	if len(rule.Spec.Groups) == 0 {
+		return "", "", errors.New("spec.groups defines no rule group")
+	}
+
+	for i := range rule.Spec.Groups {
+		group := &rule.Spec.Groups[i]
+		if group.Kind != yaml.MappingNode {
+			return "", "", fmt.Errorf("line %d: spec.groups[%d] is not a rule group", group.Line, i)
+		}
+		// The content of a mapping node alternates keys and values
+		content := make([]*yaml.Node, 0, len(group.Content))
+		for j := 0; j+1 < len(group.Content); j += 2 {
+			ignored := false
+			for _, field := range prometheusRuleIgnoredGroupFields {
+				ignored = ignored || group.Content[j].Value == field
+			}
+			if !ignored {
+				content = append(content, group.Content[j], group.Content[j+1])
+			}
+		}
+		group.Content = content
+	}
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(map[string][]yaml.Node{"groups": rule.Spec.Groups}); err != nil {
+		return "", "", err
+	}
+	if err := encoder.Close(); err != nil {
+		return "", "", err
+	}
+
+	namespace := rule.Metadata.Name
+	if rule.Metadata.Namespace != "" {
+		namespace = rule.Metadata.Namespace + "/" + rule.Metadata.Name
+	}
+	return namespace, out.String(), nil
+}
@@ -0,0 +1,140 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/statecheck"
+	"github.com/hashicorp/terraform-plugin-testing/tfjsonpath"
+	"github.com/hashicorp/terraform-plugin-testing/tfversion"
+)
+
+const testPrometheusRuleManifest = `apiVersion: monitoring.coreos.com/v1
+kind: PrometheusRule
+metadata:
+  name: api-rules
+  namespace: monitoring
+  labels:
+    release: prometheus
+spec:
+  groups:
+  - name: api
+    interval: 1m
+    partial_response_strategy: warn
+    rules:
+    - record: job:http_requests:rate5m
+      expr: sum by (job) (rate(http_requests_total[5m]))
+    - alert: APIDown
+      expr: up{job="api"} == 0
+      for: 5m
+      labels:
+        severity: critical
+`
+
+func TestParsePrometheusRule(t *testing.T) {
+	namespace, configYAML, err := parsePrometheusRule(testPrometheusRuleManifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if namespace != "monitoring/api-rules" {
+		t.Fatalf("unexpected namespace %q", namespace)
+	}
+	if strings.Contains(configYAML, "partial_response_strategy") {
+		t.Fatalf("expected partial_response_strategy to be dropped:\n%s", configYAML)
+	}
+	ruleNamespace, err := getRuleNamespaceFromYAML(context.Background(), configYAML, rulerBackendMimir)
+	if err != nil {
+		t.Fatalf("expected the groups to be valid namespace YAML, got %s:\n%s", err, configYAML)
+	}
+	if len(ruleNamespace.Groups) != 1 || len(ruleNamespace.Groups[0].Rules) != 2 || ruleNamespace.Groups[0].Rules[1].Alert.Value != "APIDown" {
+		t.Fatalf("unexpected groups:\n%s", configYAML)
+	}
+
+	for manifest, expected := range map[string]string{
+		"":                  "the manifest is empty",
+		"kind: ConfigMap\n": `expected kind PrometheusRule of apiVersion monitoring.coreos.com/v1, got kind "ConfigMap"`,
+		testPrometheusRuleManifest + "---\n" + testPrometheusRuleManifest:                       "single document",
+		"apiVersion: monitoring.coreos.com/v1\nkind: PrometheusRule\nspec:\n  groups: []\n":     "spec.groups defines no rule group",
+		"apiVersion: monitoring.coreos.com/v1\nkind: PrometheusRule\nspec:\n  groups:\n  - x\n": "spec.groups[0] is not a rule group",
+	} {
+		if _, _, err := parsePrometheusRule(manifest); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected an error containing %q, got %v", expected, err)
+		}
+	}
+}
+
+func TestFromPrometheusRuleFunction(t *testing.T) {
+	ctx := context.Background()
+	resp := function.RunResponse{Result: function.NewResultData(types.ObjectUnknown(prometheusRuleAttributeTypes))}
+	NewFromPrometheusRuleFunction().Run(ctx, function.RunRequest{
+		Arguments: function.NewArgumentsData([]attr.Value{types.StringValue(testPrometheusRuleManifest)}),
+	}, &resp)
+	if resp.Error != nil {
+		t.Fatal(resp.Error)
+	}
+	result, ok := resp.Result.Value().(types.Object)
+	if !ok {
+		t.Fatalf("unexpected result %v", resp.Result.Value())
+	}
+	if namespace := result.Attributes()["namespace"]; !namespace.Equal(types.StringValue("monitoring/api-rules")) {
+		t.Fatalf("unexpected namespace %v", namespace)
+	}
+
+	NewFromPrometheusRuleFunction().Run(ctx, function.RunRequest{
+		Arguments: function.NewArgumentsData([]attr.Value{types.StringValue("kind: ConfigMap")}),
+	}, &resp)
+	if resp.Error == nil || !strings.Contains(resp.Error.Error(), "Invalid PrometheusRule manifest") {
+		t.Fatalf("expected an argument error, got %v", resp.Error)
+	}
+}
+
+func TestAccFunctionFromPrometheusRule(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			// Provider functions are available from Terraform 1.8
+			tfversion.SkipBelow(tfversion.Version1_8_0),
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccFunctionFromPrometheusRule,
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue(
+						"mimirtool_ruler_namespace.operator",
+						tfjsonpath.New("namespace"),
+						knownvalue.StringExact("monitoring/mimir-api"),
+					),
+					SemanticYAMLStateCheck("mimirtool_ruler_namespace.operator", "remote_config_yaml", testAccFunctionFromPrometheusRuleYaml),
+				},
+			},
+		},
+	})
+}
+
+const testAccFunctionFromPrometheusRule = `
+provider "mimirtool" {
+  address = "http://localhost:8080"
+}
+
+locals {
+  prometheus_rule = provider::mimirtool::from_prometheus_rule(file("testdata/prometheus_rule.yaml"))
+}
+
+resource "mimirtool_ruler_namespace" "operator" {
+	namespace = local.prometheus_rule.namespace
+	config_yaml = local.prometheus_rule.config_yaml
+  }
+`
+
+const testAccFunctionFromPrometheusRuleYaml = `groups:
+- name: mimir_api_1
+  rules:
+  - record: cluster_job:cortex_request_duration_seconds:99quantile
+    expr: histogram_quantile(0.99, sum by (le, cluster, job) (rate(cortex_request_duration_seconds_bucket[1m])))
+`
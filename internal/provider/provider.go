@@ -12,15 +12,20 @@ import (
 	mimirtool "github.com/grafana/mimir/pkg/mimirtool/client"
 	mimirVersion "github.com/grafana/mimir/pkg/util/version"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/function"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
 // Ensure MimirtoolProvider satisfies various provider interfaces.
-var _ provider.Provider = &MimirtoolProvider{}
+var (
+	_ provider.Provider              = &MimirtoolProvider{}
+	_ provider.ProviderWithFunctions = &MimirtoolProvider{}
+)
 
 // MimirtoolProvider defines the provider implementation.
 type MimirtoolProvider struct {
@@ -36,28 +41,47 @@ type MimirClientConfig struct {
 	TenantID               string
 	APIUser                string
 	APIKey                 string
+	APIKeyFile             string
 	AuthToken              string
+	AuthTokenFile          string
 	TLSKeyPath             string
 	TLSCertPath            string
 	TLSCAPath              string
+	TLSKeyPEM              string
+	TLSCertPEM             string
+	TLSCAPEM               string
 	InsecureSkipVerify     bool
 	PrometheusHTTPPrefix   string
 	AlertmanagerHTTPPrefix string
+	StartupProbe           bool
+	// RulerBackend selects the routes of the ruler API, mimir when empty
+	RulerBackend string
 }
 
 // MimirtoolProviderModel describes the provider data model.
 type MimirtoolProviderModel struct {
-	Address                types.String `tfsdk:"address"`
-	TenantID               types.String `tfsdk:"tenant_id"`
-	APIUser                types.String `tfsdk:"api_user"`
-	APIKey                 types.String `tfsdk:"api_key"`
-	AuthToken              types.String `tfsdk:"auth_token"`
-	TLSKeyPath             types.String `tfsdk:"tls_key_path"`
-	TLSCertPath            types.String `tfsdk:"tls_cert_path"`
-	TLSCAPath              types.String `tfsdk:"tls_ca_path"`
-	InsecureSkipVerify     types.Bool   `tfsdk:"insecure_skip_verify"`
-	PrometheusHTTPPrefix   types.String `tfsdk:"prometheus_http_prefix"`
-	AlertmanagerHTTPPrefix types.String `tfsdk:"alertmanager_http_prefix"`
+	Address                    types.String      `tfsdk:"address"`
+	TenantID                   types.String      `tfsdk:"tenant_id"`
+	APIUser                    types.String      `tfsdk:"api_user"`
+	APIKey                     types.String      `tfsdk:"api_key"`
+	APIKeyFile                 types.String      `tfsdk:"api_key_file"`
+	AuthToken                  types.String      `tfsdk:"auth_token"`
+	AuthTokenFile              types.String      `tfsdk:"auth_token_file"`
+	TLSKeyPath                 types.String      `tfsdk:"tls_key_path"`
+	TLSCertPath                types.String      `tfsdk:"tls_cert_path"`
+	TLSCAPath                  types.String      `tfsdk:"tls_ca_path"`
+	TLSKeyPEM                  types.String      `tfsdk:"tls_key_pem"`
+	TLSCertPEM                 types.String      `tfsdk:"tls_cert_pem"`
+	TLSCAPEM                   types.String      `tfsdk:"tls_ca_pem"`
+	InsecureSkipVerify         types.Bool        `tfsdk:"insecure_skip_verify"`
+	PrometheusHTTPPrefix       types.String      `tfsdk:"prometheus_http_prefix"`
+	AlertmanagerHTTPPrefix     types.String      `tfsdk:"alertmanager_http_prefix"`
+	StartupProbe               types.Bool        `tfsdk:"startup_probe"`
+	FederatedRules             types.Bool        `tfsdk:"federated_rules"`
+	UnsupportedRuleGroupFields types.String      `tfsdk:"unsupported_rule_group_fields"`
+	DefaultRuleLabels          types.Map         `tfsdk:"default_rule_labels"`
+	RulePolicy                 *rulePolicyModel  `tfsdk:"rule_policy"`
+	RulerLimits                *rulerLimitsModel `tfsdk:"ruler_limits"`
 }
 
 func (p *MimirtoolProvider) Metadata(_ context.Context, _ provider.MetadataRequest, resp *provider.MetadataResponse) {
@@ -86,11 +110,19 @@ func (p *MimirtoolProvider) Schema(_ context.Context, _ provider.SchemaRequest,
 				Optional:            true,
 				Sensitive:           true,
 			},
+			"api_key_file": schema.StringAttribute{
+				MarkdownDescription: "Path to a file containing the API key to use when contacting Grafana Mimir. The file is read each time a request is sent, so the key can be rotated while Terraform runs. Conflicts with `api_key`. May alternatively be set via the `MIMIRTOOL_API_KEY_FILE` or `MIMIR_API_KEY_FILE` environment variable.",
+				Optional:            true,
+			},
 			"auth_token": schema.StringAttribute{
 				MarkdownDescription: "Authentication token for bearer token or JWT auth when contacting Grafana Mimir. May alternatively be set via the `MIMIRTOOL_AUTH_TOKEN` or `MIMIR_AUTH_TOKEN` environment variable.",
 				Optional:            true,
 				Sensitive:           true,
 			},
+			"auth_token_file": schema.StringAttribute{
+				MarkdownDescription: "Path to a file containing the authentication token for bearer token or JWT auth. The file is read each time a request is sent, so the token can be rotated while Terraform runs. Conflicts with `auth_token`. May alternatively be set via the `MIMIRTOOL_AUTH_TOKEN_FILE` or `MIMIR_AUTH_TOKEN_FILE` environment variable.",
+				Optional:            true,
+			},
 			"tls_key_path": schema.StringAttribute{
 				MarkdownDescription: "Client TLS key file to use to authenticate to the MIMIR server. May alternatively be set via the `MIMIRTOOL_TLS_KEY_PATH` or `MIMIR_TLS_KEY_PATH` environment variable.",
 				Optional:            true,
@@ -103,6 +135,21 @@ func (p *MimirtoolProvider) Schema(_ context.Context, _ provider.SchemaRequest,
 				MarkdownDescription: "Certificate CA bundle to use to verify the MIMIR server's certificate. May alternatively be set via the `MIMIRTOOL_TLS_CA_PATH` or `MIMIR_TLS_CA_PATH` environment variable.",
 				Optional:            true,
 			},
+			"tls_key_pem": schema.StringAttribute{
+				MarkdownDescription: "Client TLS key in PEM format to use to authenticate to the MIMIR server. Conflicts with `tls_key_path`. May alternatively be set via the `MIMIRTOOL_TLS_KEY_PEM` or `MIMIR_TLS_KEY_PEM` environment variable.",
+				Optional:            true,
+				Sensitive:           true,
+			},
+			"tls_cert_pem": schema.StringAttribute{
+				MarkdownDescription: "Client TLS certificate in PEM format to use to authenticate to the MIMIR server. Conflicts with `tls_cert_path`. May alternatively be set via the `MIMIRTOOL_TLS_CERT_PEM` or `MIMIR_TLS_CERT_PEM` environment variable.",
+				Optional:            true,
+				Sensitive:           true,
+			},
+			"tls_ca_pem": schema.StringAttribute{
+				MarkdownDescription: "Certificate CA bundle in PEM format to use to verify the MIMIR server's certificate. Conflicts with `tls_ca_path`. May alternatively be set via the `MIMIRTOOL_TLS_CA_PEM` or `MIMIR_TLS_CA_PEM` environment variable.",
+				Optional:            true,
+				Sensitive:           true,
+			},
 			"insecure_skip_verify": schema.BoolAttribute{
 				MarkdownDescription: "Skip TLS certificate verification. May alternatively be set via the `MIMIRTOOL_INSECURE_SKIP_VERIFY` or `MIMIR_INSECURE_SKIP_VERIFY` environment variable.",
 				Optional:            true,
@@ -115,6 +162,37 @@ func (p *MimirtoolProvider) Schema(_ context.Context, _ provider.SchemaRequest,
 				MarkdownDescription: "Path prefix to use for alertmanager. May alternatively be set via the `MIMIRTOOL_ALERTMANAGER_HTTP_PREFIX` or `MIMIR_ALERTMANAGER_HTTP_PREFIX` environment variable.",
 				Optional:            true,
 			},
+			"startup_probe": schema.BoolAttribute{
+				MarkdownDescription: "Contact Grafana Mimir's build information and readiness endpoints when the provider is configured. Connectivity and authentication problems are then reported once, and the Mimir version is used to validate rule group fields at plan time. May alternatively be set via the `MIMIRTOOL_STARTUP_PROBE` or `MIMIR_STARTUP_PROBE` environment variable.",
+				Optional:            true,
+			},
+			"unsupported_rule_group_fields": schema.StringAttribute{
+				MarkdownDescription: "How rule group fields unsupported by the Grafana Mimir version reported by the startup probe, which Mimir would silently drop, are reported at plan time: " +
+					"`fail` (default) as errors or `warn` as warnings. May alternatively be set via the `MIMIRTOOL_UNSUPPORTED_RULE_GROUP_FIELDS` or `MIMIR_UNSUPPORTED_RULE_GROUP_FIELDS` environment variable.",
+				Optional: true,
+				Validators: []validator.String{
+					stringOneOfValidator{values: []string{unsupportedRuleGroupFieldsWarn, unsupportedRuleGroupFieldsFail}},
+				},
+			},
+			"federated_rules": schema.BoolAttribute{
+				MarkdownDescription: "Whether federated rule groups, which set `source_tenants`, are enabled in Grafana Mimir (`-ruler.tenant-federation.enabled`). " +
+					"Rule groups setting `source_tenants` are rejected at plan time when disabled. " +
+					"Defaults to the `federated_rules` feature reported by the startup probe, they are not checked when neither is known.",
+				Optional: true,
+			},
+			"default_rule_labels": schema.MapAttribute{
+				MarkdownDescription: "Labels merged into every alerting and recording rule of the `mimirtool_ruler_namespace` and `mimirtool_slo` resources, e.g. `team` or `env`. " +
+					"They have the lowest precedence: the resource `extra_labels` and the labels set on a rule override them.",
+				ElementType: types.StringType,
+				Optional:    true,
+				Validators: []validator.Map{
+					labelNamesMapValidator{},
+				},
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"rule_policy":  rulePolicyProviderBlock(),
+			"ruler_limits": rulerLimitsProviderBlock(),
 		},
 	}
 }
@@ -134,13 +212,19 @@ func (p *MimirtoolProvider) Configure(ctx context.Context, req provider.Configur
 		TenantID:               getStringValue(data.TenantID, "MIMIRTOOL_TENANT_ID", "MIMIR_TENANT_ID", ""),
 		APIUser:                getStringValue(data.APIUser, "MIMIRTOOL_API_USER", "MIMIR_API_USER", ""),
 		APIKey:                 getStringValue(data.APIKey, "MIMIRTOOL_API_KEY", "MIMIR_API_KEY", ""),
+		APIKeyFile:             getStringValue(data.APIKeyFile, "MIMIRTOOL_API_KEY_FILE", "MIMIR_API_KEY_FILE", ""),
 		AuthToken:              getStringValue(data.AuthToken, "MIMIRTOOL_AUTH_TOKEN", "MIMIR_AUTH_TOKEN", ""),
+		AuthTokenFile:          getStringValue(data.AuthTokenFile, "MIMIRTOOL_AUTH_TOKEN_FILE", "MIMIR_AUTH_TOKEN_FILE", ""),
 		TLSKeyPath:             getStringValue(data.TLSKeyPath, "MIMIRTOOL_TLS_KEY_PATH", "MIMIR_TLS_KEY_PATH", ""),
 		TLSCertPath:            getStringValue(data.TLSCertPath, "MIMIRTOOL_TLS_CERT_PATH", "MIMIR_TLS_CERT_PATH", ""),
 		TLSCAPath:              getStringValue(data.TLSCAPath, "MIMIRTOOL_TLS_CA_PATH", "MIMIR_TLS_CA_PATH", ""),
+		TLSKeyPEM:              getStringValue(data.TLSKeyPEM, "MIMIRTOOL_TLS_KEY_PEM", "MIMIR_TLS_KEY_PEM", ""),
+		TLSCertPEM:             getStringValue(data.TLSCertPEM, "MIMIRTOOL_TLS_CERT_PEM", "MIMIR_TLS_CERT_PEM", ""),
+		TLSCAPEM:               getStringValue(data.TLSCAPEM, "MIMIRTOOL_TLS_CA_PEM", "MIMIR_TLS_CA_PEM", ""),
 		InsecureSkipVerify:     getBoolValue(data.InsecureSkipVerify, "MIMIRTOOL_INSECURE_SKIP_VERIFY", "MIMIR_INSECURE_SKIP_VERIFY", false),
 		PrometheusHTTPPrefix:   getStringValue(data.PrometheusHTTPPrefix, "MIMIRTOOL_PROMETHEUS_HTTP_PREFIX", "MIMIR_PROMETHEUS_HTTP_PREFIX", "/prometheus"),
 		AlertmanagerHTTPPrefix: getStringValue(data.AlertmanagerHTTPPrefix, "MIMIRTOOL_ALERTMANAGER_HTTP_PREFIX", "MIMIR_ALERTMANAGER_HTTP_PREFIX", "/alertmanager"),
+		StartupProbe:           getBoolValue(data.StartupProbe, "MIMIRTOOL_STARTUP_PROBE", "MIMIR_STARTUP_PROBE", false),
 	}
 
 	tflog.Info(ctx, "Configured Mimirtool provider", map[string]interface{}{
@@ -161,10 +245,38 @@ func (p *MimirtoolProvider) Configure(ctx context.Context, req provider.Configur
 		return
 	}
 
+	// Inline and file based settings are alternatives to each other
+	for _, conflict := range []struct {
+		attribute, alternative string
+		set                    bool
+	}{
+		{"api_key", "api_key_file", clientConfig.APIKey != "" && clientConfig.APIKeyFile != ""},
+		{"auth_token", "auth_token_file", clientConfig.AuthToken != "" && clientConfig.AuthTokenFile != ""},
+		{"tls_key_path", "tls_key_pem", clientConfig.TLSKeyPath != "" && clientConfig.TLSKeyPEM != ""},
+		{"tls_cert_path", "tls_cert_pem", clientConfig.TLSCertPath != "" && clientConfig.TLSCertPEM != ""},
+		{"tls_ca_path", "tls_ca_pem", clientConfig.TLSCAPath != "" && clientConfig.TLSCAPEM != ""},
+	} {
+		if conflict.set {
+			resp.Diagnostics.AddError(
+				"Conflicting Configuration",
+				fmt.Sprintf("The provider cannot create the Mimir client as both %q and %q are set. "+
+					"Set only one of them in the configuration or in the environment.", conflict.attribute, conflict.alternative),
+			)
+		}
+	}
+	if (clientConfig.APIKey != "" || clientConfig.APIKeyFile != "") && (clientConfig.AuthToken != "" || clientConfig.AuthTokenFile != "") {
+		resp.Diagnostics.AddError(
+			"Conflicting Configuration",
+			"The provider cannot create the Mimir client as both basic auth (\"api_key\" or \"api_key_file\") "+
+				"and bearer auth (\"auth_token\" or \"auth_token_file\") are configured. Set only one of them.",
+		)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	// Create a new Mimirtool client using the configuration values
-	var err error
-	c := &myClient{}
-	c.cli, err = getDefaultMimirClient(clientConfig, p.version)
+	cli, err := getDefaultMimirClient(clientConfig, p.version)
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to Create Mimirtool API Client",
@@ -174,37 +286,104 @@ func (p *MimirtoolProvider) Configure(ctx context.Context, req provider.Configur
 		)
 		return
 	}
+	c := &myClient{
+		cli:               cli,
+		rulerClients:      map[string]mimirClientInterface{rulerBackendMimir: cli},
+		defaultRuleLabels: stringMapValue(data.DefaultRuleLabels),
+		rulePolicy:        data.RulePolicy,
+		rulerLimits:       newRulerLimitsSource(&cli.Client, clientConfig, data.RulerLimits),
+	}
+	for backend := range rulerBackendHTTPPrefixes {
+		backendConfig := clientConfig
+		backendConfig.RulerBackend = backend
+		backendCli, err := getDefaultMimirClient(backendConfig, p.version)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Unable to Create Mimirtool API Client",
+				fmt.Sprintf("An unexpected error occurred when creating the Mimirtool API client of the %s ruler. ", backend)+
+					"If the error is not clear, please contact the provider developers.\n\n"+
+					"Mimirtool Client Error: "+err.Error(),
+			)
+			return
+		}
+		c.rulerClients[backend] = backendCli
+	}
+
+	if clientConfig.StartupProbe {
+		info, ok := probeMimir(ctx, &cli.Client, clientConfig, &resp.Diagnostics)
+		if !ok {
+			return
+		}
+		c.mimirVersion = parseMimirVersion(ctx, info.Version)
+		c.features = info.Features
+	}
+	c.federatedRules = federatedRulesEnabled(data.FederatedRules, c.features)
+	c.unsupportedRuleGroupFields = getStringValue(data.UnsupportedRuleGroupFields,
+		"MIMIRTOOL_UNSUPPORTED_RULE_GROUP_FIELDS", "MIMIR_UNSUPPORTED_RULE_GROUP_FIELDS", unsupportedRuleGroupFieldsFail)
 
-	resp.DataSourceData = c.cli
-	resp.ResourceData = c.cli
+	resp.DataSourceData = c
+	resp.ResourceData = c
 }
 
-func getDefaultMimirClient(cfg MimirClientConfig, version string) (mimirClientInterface, error) {
+func getDefaultMimirClient(cfg MimirClientConfig, version string) (*mimirtool.MimirClient, error) {
 	mimirVersion.Version = fmt.Sprintf("terraform-provider-mimirtool-%s", version)
-	return mimirtool.New(mimirtool.Config{
-		AuthToken: cfg.AuthToken,
-		User:      cfg.APIUser,
-		Key:       cfg.APIKey,
-		Address:   cfg.Address,
-		ID:        cfg.TenantID,
+	reader := inlineSecretReader{}
+	// Cortex and Loki serve the ruler API on the legacy routes
+	rulerHTTPPrefix, legacyRoutes := rulerBackendHTTPPrefixes[cfg.RulerBackend]
+	cli, err := mimirtool.New(mimirtool.Config{
+		AuthToken:       cfg.AuthToken,
+		User:            cfg.APIUser,
+		Key:             cfg.APIKey,
+		Address:         cfg.Address,
+		ID:              cfg.TenantID,
+		UseLegacyRoutes: legacyRoutes,
+		MimirHTTPPrefix: rulerHTTPPrefix,
 		TLS: tls.ClientConfig{
-			CAPath:             cfg.TLSCAPath,
-			CertPath:           cfg.TLSCertPath,
-			KeyPath:            cfg.TLSKeyPath,
+			CAPath:             reader.add(cfg.TLSCAPath, "tls_ca_pem", cfg.TLSCAPEM),
+			CertPath:           reader.add(cfg.TLSCertPath, "tls_cert_pem", cfg.TLSCertPEM),
+			KeyPath:            reader.add(cfg.TLSKeyPath, "tls_key_pem", cfg.TLSKeyPEM),
 			InsecureSkipVerify: cfg.InsecureSkipVerify,
+			Reader:             reader,
 		},
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.AuthTokenFile != "" || cfg.APIKeyFile != "" {
+		basicAuthUser := cfg.APIUser
+		if basicAuthUser == "" {
+			basicAuthUser = cfg.TenantID
+		}
+		cli.Client.Transport = &credentialsFileTransport{
+			base:          cli.Client.Transport,
+			authTokenFile: cfg.AuthTokenFile,
+			apiKeyFile:    cfg.APIKeyFile,
+			basicAuthUser: basicAuthUser,
+		}
+	}
+	return cli, nil
 }
 
 func (p *MimirtoolProvider) Resources(_ context.Context) []func() resource.Resource {
 	return []func() resource.Resource{
 		NewRulerNamespaceResource,
 		NewAlertmanagerResource,
+		NewSLOResource,
 	}
 }
 
 func (p *MimirtoolProvider) DataSources(_ context.Context) []func() datasource.DataSource {
-	return []func() datasource.DataSource{}
+	return []func() datasource.DataSource{
+		NewRulerNamespaceDataSource,
+		NewMixinRulesDataSource,
+	}
+}
+
+func (p *MimirtoolProvider) Functions(_ context.Context) []func() function.Function {
+	return []func() function.Function{
+		NewFromPrometheusRuleFunction,
+	}
 }
 
 func New(version string) func() provider.Provider {
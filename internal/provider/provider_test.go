@@ -4,6 +4,8 @@
 package provider
 
 import (
+	"testing"
+
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
 	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
 )
@@ -14,3 +16,14 @@ import (
 var testAccProtoV6ProviderFactories = map[string]func() (tfprotov6.ProviderServer, error){
 	"mimirtool": providerserver.NewProtocol6WithError(New("test")()),
 }
+
+// testAccMimirClient returns a client to the Grafana Mimir used by the
+// acceptance tests, to change resources behind Terraform's back.
+func testAccMimirClient(t *testing.T) mimirClientInterface {
+	t.Helper()
+	cli, err := getDefaultMimirClient(MimirClientConfig{Address: "http://localhost:8080"}, "test")
+	if err != nil {
+		t.Fatalf("failed to create Mimir client: %s", err)
+	}
+	return cli
+}
@@ -0,0 +1,255 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/grafana/mimir/pkg/mimirtool/rules"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	providerschema "github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	resourceschema "github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+const (
+	rulePolicyEnforcementWarn = "warn"
+	rulePolicyEnforcementFail = "fail"
+)
+
+// rulePolicyModel is the rule_policy block, available on the provider and on
+// the mimirtool_ruler_namespace resource.
+type rulePolicyModel struct {
+	RequiredLabels      types.Set    `tfsdk:"required_labels"`
+	RequiredAnnotations types.Set    `tfsdk:"required_annotations"`
+	AllowedSeverities   types.Set    `tfsdk:"allowed_severities"`
+	MinFor              types.String `tfsdk:"min_for"`
+	BannedMetricNames   types.Set    `tfsdk:"banned_metric_names"`
+	BannedLabelRegexes  types.Set    `tfsdk:"banned_label_regexes"`
+	Enforcement         types.String `tfsdk:"enforcement"`
+}
+
+var rulePolicyDescriptions = map[string]string{
+	"":                     "Organization-wide guardrails checked against every rule of the ruler namespaces.",
+	"required_labels":      "Labels every alerting rule must set, e.g. `severity`.",
+	"required_annotations": "Annotations every alerting rule must set, e.g. `runbook_url`.",
+	"allowed_severities":   "Allowed values of the `severity` label of the alerting rules.",
+	"min_for":              "Minimum `for` duration of the alerting rules, e.g. `1m`.",
+	"banned_metric_names":  "Metric names the expressions must not select.",
+	"banned_label_regexes": "Regular expressions of label names the rules must neither set nor use in the selectors of their expressions, e.g. `pod|instance`. They are fully anchored.",
+	"enforcement":          "How violations are reported: `fail` (default) as errors, `warn` as warnings.",
+}
+
+func rulePolicyProviderBlock() providerschema.SingleNestedBlock {
+	return providerschema.SingleNestedBlock{
+		MarkdownDescription: rulePolicyDescriptions[""] + " Each attribute can be overridden by the `rule_policy` block of the `mimirtool_ruler_namespace` resource.",
+		Attributes: map[string]providerschema.Attribute{
+			"required_labels":      providerschema.SetAttribute{MarkdownDescription: rulePolicyDescriptions["required_labels"], ElementType: types.StringType, Optional: true},
+			"required_annotations": providerschema.SetAttribute{MarkdownDescription: rulePolicyDescriptions["required_annotations"], ElementType: types.StringType, Optional: true},
+			"allowed_severities":   providerschema.SetAttribute{MarkdownDescription: rulePolicyDescriptions["allowed_severities"], ElementType: types.StringType, Optional: true},
+			"min_for": providerschema.StringAttribute{
+				MarkdownDescription: rulePolicyDescriptions["min_for"],
+				Optional:            true,
+				Validators:          []validator.String{durationValidator{}},
+			},
+			"banned_metric_names": providerschema.SetAttribute{MarkdownDescription: rulePolicyDescriptions["banned_metric_names"], ElementType: types.StringType, Optional: true},
+			"banned_label_regexes": providerschema.SetAttribute{
+				MarkdownDescription: rulePolicyDescriptions["banned_label_regexes"],
+				ElementType:         types.StringType,
+				Optional:            true,
+				Validators:          []validator.Set{regexpSetValidator{}},
+			},
+			"enforcement": providerschema.StringAttribute{
+				MarkdownDescription: rulePolicyDescriptions["enforcement"],
+				Optional:            true,
+				Validators:          []validator.String{stringOneOfValidator{values: []string{rulePolicyEnforcementWarn, rulePolicyEnforcementFail}}},
+			},
+		},
+	}
+}
+
+func rulePolicyResourceBlock() resourceschema.SingleNestedBlock {
+	return resourceschema.SingleNestedBlock{
+		MarkdownDescription: rulePolicyDescriptions[""] + " Each attribute set here overrides the one of the provider `rule_policy` block, " +
+			"`enforcement` only applies to the attributes set here.",
+		Attributes: map[string]resourceschema.Attribute{
+			"required_labels":      resourceschema.SetAttribute{MarkdownDescription: rulePolicyDescriptions["required_labels"], ElementType: types.StringType, Optional: true},
+			"required_annotations": resourceschema.SetAttribute{MarkdownDescription: rulePolicyDescriptions["required_annotations"], ElementType: types.StringType, Optional: true},
+			"allowed_severities":   resourceschema.SetAttribute{MarkdownDescription: rulePolicyDescriptions["allowed_severities"], ElementType: types.StringType, Optional: true},
+			"min_for": resourceschema.StringAttribute{
+				MarkdownDescription: rulePolicyDescriptions["min_for"],
+				Optional:            true,
+				Validators:          []validator.String{durationValidator{}},
+			},
+			"banned_metric_names": resourceschema.SetAttribute{MarkdownDescription: rulePolicyDescriptions["banned_metric_names"], ElementType: types.StringType, Optional: true},
+			"banned_label_regexes": resourceschema.SetAttribute{
+				MarkdownDescription: rulePolicyDescriptions["banned_label_regexes"],
+				ElementType:         types.StringType,
+				Optional:            true,
+				Validators:          []validator.Set{regexpSetValidator{}},
+			},
+			"enforcement": resourceschema.StringAttribute{
+				MarkdownDescription: rulePolicyDescriptions["enforcement"],
+				Optional:            true,
+				Validators:          []validator.String{stringOneOfValidator{values: []string{rulePolicyEnforcementWarn, rulePolicyEnforcementFail}}},
+			},
+		},
+	}
+}
+
+// withoutOverrides returns the policy without the attributes set in overrides,
+// which are checked on their own with their own enforcement.
+func (p rulePolicyModel) withoutOverrides(overrides *rulePolicyModel) rulePolicyModel {
+	if overrides == nil {
+		return p
+	}
+	if !overrides.RequiredLabels.IsNull() {
+		p.RequiredLabels = types.SetNull(types.StringType)
+	}
+	if !overrides.RequiredAnnotations.IsNull() {
+		p.RequiredAnnotations = types.SetNull(types.StringType)
+	}
+	if !overrides.AllowedSeverities.IsNull() {
+		p.AllowedSeverities = types.SetNull(types.StringType)
+	}
+	if !overrides.MinFor.IsNull() {
+		p.MinFor = types.StringNull()
+	}
+	if !overrides.BannedMetricNames.IsNull() {
+		p.BannedMetricNames = types.SetNull(types.StringType)
+	}
+	if !overrides.BannedLabelRegexes.IsNull() {
+		p.BannedLabelRegexes = types.SetNull(types.StringType)
+	}
+	return p
+}
+
+// withoutLabelChecks returns the policy without the attributes depending on
+// the labels of the rules, which are only known at plan time once the provider
+// default_rule_labels are merged and the selectors are rewritten.
+func (p rulePolicyModel) withoutLabelChecks() rulePolicyModel {
+	p.RequiredLabels = types.SetNull(types.StringType)
+	p.AllowedSeverities = types.SetNull(types.StringType)
+	p.BannedLabelRegexes = types.SetNull(types.StringType)
+	return p
+}
+
+// labelChecks returns the attributes of the policy left out by withoutLabelChecks.
+func (p rulePolicyModel) labelChecks() rulePolicyModel {
+	p.RequiredAnnotations = types.SetNull(types.StringType)
+	p.MinFor = types.StringNull()
+	p.BannedMetricNames = types.SetNull(types.StringType)
+	return p
+}
+
+// check returns the violations of the policy by the rules of the namespace.
+// Invalid policy settings are reported by the attribute validators and ignored here.
+func (p rulePolicyModel) check(ruleNamespace rules.RuleNamespace) []string {
+	requiredLabels := stringSetValue(p.RequiredLabels)
+	requiredAnnotations := stringSetValue(p.RequiredAnnotations)
+	allowedSeverities := stringSetValue(p.AllowedSeverities)
+	bannedMetricNames := stringSetValue(p.BannedMetricNames)
+	var minFor time.Duration
+	if d, err := model.ParseDuration(p.MinFor.ValueString()); err == nil {
+		minFor = time.Duration(d)
+	}
+	var bannedLabels []*regexp.Regexp
+	for _, expr := range stringSetValue(p.BannedLabelRegexes) {
+		if re, err := regexp.Compile("^(?:" + expr + ")$"); err == nil {
+			bannedLabels = append(bannedLabels, re)
+		}
+	}
+	isBannedLabel := func(name string) bool {
+		return slices.ContainsFunc(bannedLabels, func(re *regexp.Regexp) bool { return re.MatchString(name) })
+	}
+
+	var violations []string
+	for _, group := range ruleNamespace.Groups {
+		for _, rule := range group.Rules {
+			report := func(format string, args ...any) {
+				violations = append(violations, fmt.Sprintf("group %q, rule %q: ", group.Name, ruleName(rule))+fmt.Sprintf(format, args...))
+			}
+
+			if rule.Alert.Value != "" {
+				for _, name := range requiredLabels {
+					if _, ok := rule.Labels[name]; !ok {
+						report("the alerting rule must set the %q label", name)
+					}
+				}
+				for _, name := range requiredAnnotations {
+					if _, ok := rule.Annotations[name]; !ok {
+						report("the alerting rule must set the %q annotation", name)
+					}
+				}
+				if severity, ok := rule.Labels["severity"]; ok && len(allowedSeverities) > 0 && !slices.Contains(allowedSeverities, severity) {
+					report("severity %q is not one of: %s", severity, strings.Join(allowedSeverities, ", "))
+				}
+				if time.Duration(rule.For) < minFor {
+					report("for is %s, it must be at least %s", rule.For, model.Duration(minFor))
+				}
+			}
+
+			for name := range rule.Labels {
+				if isBannedLabel(name) {
+					report("the %q label is banned", name)
+				}
+			}
+
+			if len(bannedMetricNames) == 0 && len(bannedLabels) == 0 {
+				continue
+			}
+			expr, err := parser.ParseExpr(rule.Expr.Value)
+			if err != nil {
+				// Reported by the expression lint
+				continue
+			}
+			parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+				selector, ok := node.(*parser.VectorSelector)
+				if !ok {
+					return nil
+				}
+				for _, matcher := range selector.LabelMatchers {
+					if matcher.Name == labels.MetricName && matcher.Type == labels.MatchEqual && slices.Contains(bannedMetricNames, matcher.Value) {
+						report("the %q metric is banned", matcher.Value)
+					}
+					if isBannedLabel(matcher.Name) {
+						report("selector %s uses the banned %q label", selector, matcher.Name)
+					}
+				}
+				return nil
+			})
+		}
+	}
+	return violations
+}
+
+// addRulePolicyDiagnostics reports the violations as errors or warnings
+// depending on the enforcement of the policy.
+func addRulePolicyDiagnostics(diagnostics *diag.Diagnostics, attributePath path.Path, policy rulePolicyModel, violations []string) {
+	for _, violation := range violations {
+		if policy.Enforcement.ValueString() == rulePolicyEnforcementWarn {
+			diagnostics.AddAttributeWarning(attributePath, "Rule Policy Violation", violation)
+		} else {
+			diagnostics.AddAttributeError(attributePath, "Rule Policy Violation", violation)
+		}
+	}
+}
+
+// stringSetValue converts a set attribute, unknown and null values are empty
+func stringSetValue(value types.Set) []string {
+	values := make([]string, 0, len(value.Elements()))
+	for _, element := range value.Elements() {
+		if v, ok := element.(types.String); ok && !v.IsUnknown() && !v.IsNull() {
+			values = append(values, v.ValueString())
+		}
+	}
+	slices.Sort(values)
+	return values
+}
@@ -0,0 +1,155 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func testStringSet(values ...string) types.Set {
+	elements := make([]attr.Value, 0, len(values))
+	for _, v := range values {
+		elements = append(elements, types.StringValue(v))
+	}
+	return types.SetValueMust(types.StringType, elements)
+}
+
+func TestRulePolicyCheck(t *testing.T) {
+	ruleNamespace, err := getRuleNamespaceFromYAML(context.Background(), `groups:
+- name: g1
+  rules:
+  - alert: Good
+    expr: up == 0
+    for: 5m
+    labels:
+      severity: critical
+    annotations:
+      runbook_url: https://runbooks/good
+  - alert: Bad
+    expr: sum by (pod) (kube_pod_info{pod=~"api.*"}) == 0
+    labels:
+      severity: page
+  - record: pod:up:sum
+    expr: sum by (pod) (up)
+    labels:
+      pod_name: x
+`, rulerBackendMimir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy := rulePolicyModel{
+		RequiredLabels:      testStringSet("severity"),
+		RequiredAnnotations: testStringSet("runbook_url"),
+		AllowedSeverities:   testStringSet("critical", "warning"),
+		MinFor:              types.StringValue("1m"),
+		BannedMetricNames:   testStringSet("kube_pod_info"),
+		BannedLabelRegexes:  testStringSet("pod.*"),
+	}
+	violations := policy.check(ruleNamespace)
+	expected := []string{
+		`group "g1", rule "Bad": the alerting rule must set the "runbook_url" annotation`,
+		`group "g1", rule "Bad": severity "page" is not one of: critical, warning`,
+		`group "g1", rule "Bad": for is 0s, it must be at least 1m`,
+		`group "g1", rule "Bad": selector kube_pod_info{pod=~"api.*"} uses the banned "pod" label`,
+		`group "g1", rule "Bad": the "kube_pod_info" metric is banned`,
+		`group "g1", rule "pod:up:sum": the "pod_name" label is banned`,
+	}
+	if strings.Join(violations, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("unexpected violations:\n%s", strings.Join(violations, "\n"))
+	}
+
+	// The attributes overridden by the resource are not checked with the provider policy
+	remaining := policy.withoutOverrides(&rulePolicyModel{
+		RequiredLabels:      types.SetNull(types.StringType),
+		RequiredAnnotations: testStringSet(),
+		AllowedSeverities:   types.SetNull(types.StringType),
+		MinFor:              types.StringValue("0s"),
+		BannedMetricNames:   testStringSet(),
+		BannedLabelRegexes:  testStringSet(),
+	})
+	violations = remaining.check(ruleNamespace)
+	if len(violations) != 1 || !strings.Contains(violations[0], `severity "page"`) {
+		t.Fatalf("unexpected violations: %v", violations)
+	}
+}
+
+func TestValidateNamespaceYAMLRulePolicy(t *testing.T) {
+	const configYAML = `groups:
+- name: g1
+  rules:
+  - alert: Down
+    expr: up == 0
+    for: 5m
+`
+	model := RulerNamespaceResourceModel{
+		ExtraLabels:      types.MapNull(types.StringType),
+		ExtraAnnotations: types.MapNull(types.StringType),
+		RulePolicy: &rulePolicyModel{
+			RequiredAnnotations: testStringSet("runbook_url"),
+			MinFor:              types.StringValue("10m"),
+			// Checked at plan time, the provider default_rule_labels may set it
+			RequiredLabels: testStringSet("team"),
+		},
+	}
+
+	var diags diag.Diagnostics
+	validateNamespaceYAML(context.Background(), model, configYAML, "", path.Root("config_yaml"), &diags)
+	if diags.ErrorsCount() != 2 || !strings.Contains(diags[0].Detail(), `the alerting rule must set the "runbook_url" annotation`) ||
+		!strings.Contains(diags[1].Detail(), "for is 5m, it must be at least 10m") {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	// The annotations merged by the resource are known from the configuration
+	model.ExtraAnnotations = types.MapValueMust(types.StringType, map[string]attr.Value{"runbook_url": types.StringValue("https://runbooks/down")})
+	diags = nil
+	validateNamespaceYAML(context.Background(), model, configYAML, "", path.Root("config_yaml"), &diags)
+	if diags.ErrorsCount() != 1 || !strings.Contains(diags[0].Detail(), "for is 5m") {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+}
+
+func TestCheckRulePoliciesWithMergedLabels(t *testing.T) {
+	const configYAML = `groups:
+- name: g1
+  rules:
+  - alert: Down
+    expr: up == 0
+    for: 5m
+`
+	model := RulerNamespaceResourceModel{
+		ExtraLabels:      types.MapValueMust(types.StringType, map[string]attr.Value{"severity": types.StringValue("critical")}),
+		ExtraAnnotations: types.MapNull(types.StringType),
+		RulePolicy:       &rulePolicyModel{RequiredLabels: testStringSet("team")},
+	}
+	providerPolicy := &rulePolicyModel{RequiredLabels: testStringSet("severity")}
+
+	for name, tc := range map[string]struct {
+		defaultLabels map[string]string
+		violations    int
+	}{
+		"labels from the resource and the provider": {defaultLabels: map[string]string{"team": "api"}},
+		"missing provider default label":            {violations: 1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ruleNamespace, err := getRuleNamespaceFromYAML(context.Background(), configYAML, rulerBackendMimir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			prepared, _, err := model.preparedConfigYAML(ruleNamespace, tc.defaultLabels)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var diags diag.Diagnostics
+			model.checkRulePolicies(prepared, providerPolicy, &diags)
+			if diags.ErrorsCount() != tc.violations {
+				t.Fatalf("expected %d violations, got %v", tc.violations, diags)
+			}
+		})
+	}
+}
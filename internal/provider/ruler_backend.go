@@ -0,0 +1,214 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/grafana/mimir/pkg/mimirtool/rules"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	"gopkg.in/yaml.v3"
+)
+
+// Ruler backends supported by mimirtool_ruler_namespace. Grafana Mimir and
+// Cortex rules use PromQL, Loki rules use LogQL.
+const (
+	rulerBackendMimir  = rules.MimirBackend
+	rulerBackendCortex = "cortex"
+	rulerBackendLoki   = "loki"
+)
+
+var rulerBackends = []string{rulerBackendMimir, rulerBackendCortex, rulerBackendLoki}
+
+// rulerBackendHTTPPrefixes are the path prefixes of the ruler configuration
+// API of the backends served on the legacy /api/v1/rules routes.
+var rulerBackendHTTPPrefixes = map[string]string{
+	rulerBackendCortex: "",
+	rulerBackendLoki:   "/loki",
+}
+
+// usesPromQL tells whether the expressions of the backend are PromQL, the
+// LogQL expressions of Loki are only partially checked by checkLogQLExpression.
+func usesPromQL(backend string) bool {
+	return backend != rulerBackendLoki
+}
+
+// parseLogQLRuleNamespaces is rules.ParseBytes for LogQL rules: the groups and
+// rules are validated the same way, and the expressions with checkLogQLExpression.
+func parseLogQLRuleNamespaces(content []byte) ([]rules.RuleNamespace, []error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+
+	var ruleNamespaces []rules.RuleNamespace
+	for {
+		var ruleNamespace rules.RuleNamespace
+		err := decoder.Decode(&ruleNamespace)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, []error{err}
+		}
+		if errs := validateLogQLRuleNamespace(ruleNamespace); len(errs) > 0 {
+			return nil, errs
+		}
+		ruleNamespaces = append(ruleNamespaces, ruleNamespace)
+	}
+	return ruleNamespaces, nil
+}
+
+// validateLogQLRuleNamespace checks the groups and rules of the namespace with
+// rules.RuleNamespace.Validate, which parses the expressions as PromQL: they
+// are replaced with a placeholder and checked with checkLogQLExpression.
+func validateLogQLRuleNamespace(ruleNamespace rules.RuleNamespace) []error {
+	placeholder := ruleNamespace
+	placeholder.Groups = slices.Clone(ruleNamespace.Groups)
+	var errs []error
+	for i, group := range placeholder.Groups {
+		placeholder.Groups[i].Rules = slices.Clone(group.Rules)
+		for j, rule := range group.Rules {
+			if rule.Expr.Value == "" {
+				continue
+			}
+			if err := checkLogQLExpression(rule.Expr.Value); err != nil {
+				errs = append(errs, fmt.Errorf("group %q, rule %q: invalid LogQL expression: %w", group.Name, ruleName(rule), err))
+			}
+			placeholder.Groups[i].Rules[j].Expr.Value = "vector(0)"
+		}
+	}
+	return append(placeholder.Validate(), errs...)
+}
+
+// checkLogQLExpression checks the structure of a LogQL expression: its
+// brackets and strings are closed, and its stream selectors are valid label
+// matchers. The LogQL parser of Loki isn't used as Loki is AGPL licensed, the
+// rest of the expression is validated by Loki when the rules are written.
+func checkLogQLExpression(expr string) error {
+	closing := map[byte]byte{'(': ')', '[': ']', '{': '}'}
+	var open []int
+	for i := 0; i < len(expr); i++ {
+		switch c := expr[i]; c {
+		case '"', '`':
+			end := i + 1
+			for end < len(expr) && expr[end] != c {
+				if c == '"' && expr[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(expr) {
+				return fmt.Errorf("unterminated string opened at position %d", i)
+			}
+			i = end
+		case '#':
+			// Comment until the end of the line
+			for i < len(expr) && expr[i] != '\n' {
+				i++
+			}
+		case '(', '[', '{':
+			if len(open) > 0 && expr[open[len(open)-1]] == '{' {
+				return fmt.Errorf("unexpected %q at position %d in a stream selector", c, i)
+			}
+			open = append(open, i)
+		case ')', ']', '}':
+			if len(open) == 0 || closing[expr[open[len(open)-1]]] != c {
+				return fmt.Errorf("unexpected %q at position %d", c, i)
+			}
+			start := open[len(open)-1]
+			open = open[:len(open)-1]
+			if c != '}' {
+				continue
+			}
+			// Stream selectors have the syntax of the PromQL ones, at least one
+			// matcher must not match the empty string
+			selector := expr[start : i+1]
+			matchers, err := parser.ParseMetricSelector(selector)
+			if err != nil {
+				return fmt.Errorf("invalid stream selector %s: %w", selector, err)
+			}
+			if !slices.ContainsFunc(matchers, func(m *labels.Matcher) bool { return !m.Matches("") }) {
+				return fmt.Errorf("invalid stream selector %s: at least one matcher must not match the empty string", selector)
+			}
+		}
+	}
+	if len(open) > 0 {
+		start := open[len(open)-1]
+		return fmt.Errorf("unclosed %q opened at position %d", expr[start], start)
+	}
+	return nil
+}
+
+// rulerBackend returns the backend of the resource, mimir when not set
+func (m RulerNamespaceResourceModel) rulerBackend() string {
+	if m.Backend.IsNull() || m.Backend.IsUnknown() || m.Backend.ValueString() == "" {
+		return rulerBackendMimir
+	}
+	return m.Backend.ValueString()
+}
+
+// parseImportID splits the import ID of mimirtool_ruler_namespace, either
+// <namespace> or <backend>:<namespace> for the other backends than mimir.
+func parseImportID(id string) (string, string) {
+	for _, backend := range rulerBackends {
+		if namespace, ok := strings.CutPrefix(id, backend+":"); ok && namespace != "" {
+			return backend, namespace
+		}
+	}
+	return rulerBackendMimir, id
+}
+
+// backendRequiresReplace replaces the resource when its backend changes. A
+// null backend in the state, written before the attribute existed, is mimir,
+// so that upgrading the provider doesn't recreate every namespace.
+func backendRequiresReplace() planmodifier.String {
+	return stringplanmodifier.RequiresReplaceIf(
+		func(_ context.Context, req planmodifier.StringRequest, resp *stringplanmodifier.RequiresReplaceIfFuncResponse) {
+			previous := req.StateValue.ValueString()
+			if req.StateValue.IsNull() {
+				previous = rulerBackendMimir
+			}
+			resp.RequiresReplace = req.PlanValue.ValueString() != previous
+		},
+		"Changing the backend forces a new resource, a null backend in the state is mimir.",
+		"Changing the backend forces a new resource, a null backend in the state is `mimir`.",
+	)
+}
+
+// promQLBackendValidator rejects attributes which rewrite or evaluate PromQL
+// expressions when the backend of the resource uses another query language.
+
+type promQLBackendValidator struct{}
+
+func (v promQLBackendValidator) Description(_ context.Context) string {
+	return "Ensures the backend of the resource uses PromQL"
+}
+
+func (v promQLBackendValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v promQLBackendValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.ValueString() == "" {
+		return
+	}
+	var backend types.String
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("backend"), &backend)...)
+	if !backend.IsUnknown() && !usesPromQL(backend.ValueString()) {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Unsupported Backend",
+			fmt.Sprintf("%s is only supported by the backends using PromQL (%s, %s), not by %s.",
+				req.Path, rulerBackendMimir, rulerBackendCortex, backend.ValueString()),
+		)
+	}
+}
@@ -0,0 +1,156 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+const testLokiNamespaceYaml = `groups:
+- name: loki_alerts
+  rules:
+  - alert: HighErrorRate
+    expr: sum by (app) (rate({app="api"} |= "error" [5m])) > 10
+    for: 5m
+    labels:
+      severity: page
+  - record: app:log_lines:rate5m
+    expr: sum by (app) (rate({app="api"}[5m]))
+`
+
+func TestGetRuleNamespaceFromYAMLLoki(t *testing.T) {
+	if _, err := getRuleNamespaceFromYAML(context.Background(), testLokiNamespaceYaml, rulerBackendMimir); err == nil {
+		t.Fatal("expected the LogQL expression to be rejected as PromQL")
+	}
+
+	ruleNamespace, err := getRuleNamespaceFromYAML(context.Background(), testLokiNamespaceYaml, rulerBackendLoki)
+	if err != nil {
+		t.Fatalf("expected the LogQL rules to be valid, got %s", err)
+	}
+	if expr := ruleNamespace.Groups[0].Rules[0].Expr.Value; expr != `sum by (app) (rate({app="api"} |= "error" [5m])) > 10` {
+		t.Fatalf("expected the expression to be left as is, got %q", expr)
+	}
+
+	// The rules are still validated, except for their expressions
+	_, err = getRuleNamespaceFromYAML(context.Background(), `groups:
+- name: loki_alerts
+  rules:
+  - alert: HighErrorRate
+    record: app:errors
+    expr: count_over_time({app="api"}[5m])
+`, rulerBackendLoki)
+	if err == nil || !strings.Contains(err.Error(), "only one of 'record' and 'alert' must be set") {
+		t.Fatalf("expected an invalid rule error, got %v", err)
+	}
+}
+
+func TestCheckLogQLExpression(t *testing.T) {
+	for expr, expected := range map[string]string{
+		`sum by (app) (rate({app="api"} |= "error" [5m])) > 10`:                                  "",
+		`count_over_time({app="api", env=~"prod|staging"} | json | line_format "{{.msg}}" [1h])`: "",
+		"sum(rate({app=`a}`} |~ `\\d+ \\{` [5m]))":                                               "",
+		`vector(1)`:                          "",
+		`sum(rate({app="api"}[5m])`:          `unclosed '(' opened at position 3`,
+		`rate({app="api"}[5m]))`:             `unexpected ')' at position 21`,
+		`rate({app="api" |= "error"[5m])`:    `unexpected '[' at position 26 in a stream selector`,
+		`rate({app="api} [5m])`:              `unterminated string opened at position 10`,
+		`count_over_time({app=""}[5m])`:      `invalid stream selector {app=""}`,
+		`count_over_time({app=="api"}[5m])`:  `invalid stream selector {app=="api"}`,
+		`count_over_time({app="api"]} [5m])`: `unexpected ']' at position 26`,
+	} {
+		err := checkLogQLExpression(expr)
+		if expected == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %s", expr, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s: expected an error containing %q, got %v", expr, expected, err)
+		}
+	}
+
+	// Invalid expressions are reported when the rules are parsed
+	_, err := getRuleNamespaceFromYAML(context.Background(), `groups:
+- name: loki_alerts
+  rules:
+  - record: app:log_lines:rate5m
+    expr: sum(rate({app=""}[5m]))
+`, rulerBackendLoki)
+	if err == nil || !strings.Contains(err.Error(), `group "loki_alerts", rule "app:log_lines:rate5m": invalid LogQL expression`) {
+		t.Fatalf("expected an invalid LogQL expression error, got %v", err)
+	}
+}
+
+func TestNormalizeNamespaceYAMLLoki(t *testing.T) {
+	normalized, count, mod, err := normalizeNamespaceYAML(testLokiNamespaceYaml, rulerBackendLoki)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 || mod != 0 {
+		t.Fatalf("expected the LogQL expressions not to be linted, got count %d and mod %d", count, mod)
+	}
+	if !strings.Contains(normalized, `rate({app="api"} |= "error" [5m])`) {
+		t.Fatalf("expected the expression to be left as is, got:\n%s", normalized)
+	}
+}
+
+func TestParseImportID(t *testing.T) {
+	for id, expected := range map[string][2]string{
+		"my-namespace":        {rulerBackendMimir, "my-namespace"},
+		"loki:my-namespace":   {rulerBackendLoki, "my-namespace"},
+		"cortex:my-namespace": {rulerBackendCortex, "my-namespace"},
+		"mimir:my-namespace":  {rulerBackendMimir, "my-namespace"},
+		"other:my-namespace":  {rulerBackendMimir, "other:my-namespace"},
+		"loki:":               {rulerBackendMimir, "loki:"},
+	} {
+		backend, namespace := parseImportID(id)
+		if backend != expected[0] || namespace != expected[1] {
+			t.Errorf("import ID %q: expected backend %q and namespace %q, got %q and %q", id, expected[0], expected[1], backend, namespace)
+		}
+	}
+}
+
+func TestBackendRequiresReplace(t *testing.T) {
+	ctx := context.Background()
+	var schemaResp fwresource.SchemaResponse
+	(&RulerNamespaceResource{}).Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+	raw := func(backend types.String) tftypes.Value {
+		state := tfsdk.State{Schema: schemaResp.Schema, Raw: tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil)}
+		state.SetAttribute(ctx, path.Root("namespace"), "demo")
+		state.SetAttribute(ctx, path.Root("backend"), backend)
+		return state.Raw
+	}
+
+	for _, tc := range []struct {
+		state, plan types.String
+		replace     bool
+	}{
+		// States written before the backend attribute existed
+		{types.StringNull(), types.StringValue(rulerBackendMimir), false},
+		{types.StringNull(), types.StringValue(rulerBackendLoki), true},
+		{types.StringValue(rulerBackendMimir), types.StringValue(rulerBackendMimir), false},
+		{types.StringValue(rulerBackendMimir), types.StringValue(rulerBackendCortex), true},
+	} {
+		req := planmodifier.StringRequest{
+			Path:        path.Root("backend"),
+			State:       tfsdk.State{Schema: schemaResp.Schema, Raw: raw(tc.state)},
+			Plan:        tfsdk.Plan{Schema: schemaResp.Schema, Raw: raw(tc.plan)},
+			StateValue:  tc.state,
+			PlanValue:   tc.plan,
+			ConfigValue: tc.plan,
+		}
+		resp := &planmodifier.StringResponse{PlanValue: tc.plan}
+		backendRequiresReplace().PlanModifyString(ctx, req, resp)
+		if resp.RequiresReplace != tc.replace {
+			t.Errorf("backend %s to %s: expected RequiresReplace %t, got %t", tc.state, tc.plan, tc.replace, resp.RequiresReplace)
+		}
+	}
+}
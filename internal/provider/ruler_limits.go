@@ -0,0 +1,202 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/grafana/mimir/pkg/mimirtool/rules/rwrulefmt"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"gopkg.in/yaml.v3"
+)
+
+// userLimitsPath is the endpoint returning the effective limits of the tenant
+// See: https://grafana.com/docs/mimir/latest/references/http-api/#get-tenant-limits
+const userLimitsPath = "/api/v1/user_limits"
+
+// rulerLimitsModel is the ruler_limits block of the provider
+type rulerLimitsModel struct {
+	Fetch                  types.Bool  `tfsdk:"fetch"`
+	MaxRuleGroupsPerTenant types.Int64 `tfsdk:"max_rule_groups_per_tenant"`
+	MaxRulesPerRuleGroup   types.Int64 `tfsdk:"max_rules_per_rule_group"`
+	MaxRuleGroupSizeBytes  types.Int64 `tfsdk:"max_rule_group_size_bytes"`
+}
+
+func rulerLimitsProviderBlock() schema.SingleNestedBlock {
+	return schema.SingleNestedBlock{
+		MarkdownDescription: "Limits of the tenant enforced by the Grafana Mimir ruler. The rule groups of the `mimirtool_slo` resources and of the `mimirtool_ruler_namespace` resources using the `mimir` backend " +
+			"are checked against them, together with the rule groups of the other namespaces of the tenant, at plan time and before any write, " +
+			"so that an apply doesn't stop half-way leaving a partially updated namespace.",
+		Attributes: map[string]schema.Attribute{
+			"fetch": schema.BoolAttribute{
+				MarkdownDescription: "Fetch the effective limits of the tenant from Grafana Mimir's user limits endpoint (default `true`). The attributes set in this block take precedence.",
+				Optional:            true,
+			},
+			"max_rule_groups_per_tenant": schema.Int64Attribute{
+				MarkdownDescription: "Maximum number of rule groups of the tenant, across all namespaces (`ruler_max_rule_groups_per_tenant`). 0 means no limit.",
+				Optional:            true,
+				Validators:          []validator.Int64{nonNegativeInt64Validator{}},
+			},
+			"max_rules_per_rule_group": schema.Int64Attribute{
+				MarkdownDescription: "Maximum number of rules of a rule group (`ruler_max_rules_per_rule_group`). 0 means no limit.",
+				Optional:            true,
+				Validators:          []validator.Int64{nonNegativeInt64Validator{}},
+			},
+			"max_rule_group_size_bytes": schema.Int64Attribute{
+				MarkdownDescription: "Maximum size in bytes of the YAML payload of a rule group, e.g. the request body limit of a gateway in front of Grafana Mimir. " +
+					"It isn't reported by Grafana Mimir. 0 means no limit.",
+				Optional:   true,
+				Validators: []validator.Int64{nonNegativeInt64Validator{}},
+			},
+		},
+	}
+}
+
+// rulerLimits are the limits enforced when writing rule groups, 0 is no limit
+type rulerLimits struct {
+	MaxRuleGroupsPerTenant int `json:"ruler_max_rule_groups_per_tenant"`
+	MaxRulesPerRuleGroup   int `json:"ruler_max_rules_per_rule_group"`
+	MaxRuleGroupSizeBytes  int `json:"-"`
+}
+
+// rulerLimitsSource resolves the limits of the tenant once, on first use, as
+// they are only needed by the plans of the ruler namespaces.
+type rulerLimitsSource struct {
+	once       sync.Once
+	configured *rulerLimitsModel
+	// fetch returns the user limits endpoint response body and status
+	fetch  func(ctx context.Context) ([]byte, int, error)
+	limits rulerLimits
+}
+
+func newRulerLimitsSource(httpClient *http.Client, cfg MimirClientConfig, configured *rulerLimitsModel) *rulerLimitsSource {
+	source := &rulerLimitsSource{configured: configured}
+	if configured == nil || configured.Fetch.IsNull() || configured.Fetch.ValueBool() {
+		source.fetch = func(ctx context.Context) ([]byte, int, error) {
+			return probeRequest(ctx, httpClient, cfg, userLimitsPath)
+		}
+	}
+	return source
+}
+
+// get returns the limits of the tenant. Failing to fetch them isn't fatal,
+// e.g. older versions or gateways not exposing the endpoint: only the
+// configured limits are enforced then.
+func (s *rulerLimitsSource) get(ctx context.Context) rulerLimits {
+	s.once.Do(func() {
+		if s.fetch != nil {
+			body, status, err := s.fetch(ctx)
+			switch {
+			case err != nil:
+				tflog.Warn(ctx, "Unable to fetch the tenant limits, only the configured ruler limits are checked", map[string]interface{}{"error": err.Error()})
+			case status != http.StatusOK:
+				tflog.Warn(ctx, "Unable to fetch the tenant limits, only the configured ruler limits are checked", map[string]interface{}{"status": status, "body": string(body)})
+			default:
+				if err := json.Unmarshal(body, &s.limits); err != nil {
+					tflog.Warn(ctx, "Unable to decode the tenant limits, only the configured ruler limits are checked", map[string]interface{}{"error": err.Error()})
+					s.limits = rulerLimits{}
+				}
+			}
+		}
+		if s.configured != nil {
+			overrideLimit(&s.limits.MaxRuleGroupsPerTenant, s.configured.MaxRuleGroupsPerTenant)
+			overrideLimit(&s.limits.MaxRulesPerRuleGroup, s.configured.MaxRulesPerRuleGroup)
+			overrideLimit(&s.limits.MaxRuleGroupSizeBytes, s.configured.MaxRuleGroupSizeBytes)
+		}
+		tflog.Debug(ctx, "Resolved ruler limits", map[string]interface{}{"limits": s.limits})
+	})
+	return s.limits
+}
+
+func overrideLimit(limit *int, configured types.Int64) {
+	if !configured.IsNull() && !configured.IsUnknown() {
+		*limit = int(configured.ValueInt64())
+	}
+}
+
+// checkRulerLimits returns an error for each limit that writing the groups to
+// the namespace would exceed. tenantGroups are the rule groups of the tenant
+// by namespace before the write, the remote groups of the namespace that are
+// not declared are kept unless they are owned (all of them when owned is nil).
+func checkRulerLimits(limits rulerLimits, tenantGroups map[string][]rwrulefmt.RuleGroup, namespace string, groups []rwrulefmt.RuleGroup, owned map[string]bool) []error {
+	var errs []error
+	for _, group := range groups {
+		if limits.MaxRulesPerRuleGroup > 0 && len(group.Rules) > limits.MaxRulesPerRuleGroup {
+			errs = append(errs, fmt.Errorf("group %q has %d rules, the limit is %d rules per rule group (ruler_max_rules_per_rule_group)",
+				group.Name, len(group.Rules), limits.MaxRulesPerRuleGroup))
+		}
+		if limits.MaxRuleGroupSizeBytes > 0 {
+			// Same serialization as the mimirtool client
+			payload, err := yaml.Marshal(&group)
+			if err == nil && len(payload) > limits.MaxRuleGroupSizeBytes {
+				errs = append(errs, fmt.Errorf("group %q is %d bytes once serialized, the limit is %d bytes per rule group (max_rule_group_size_bytes)",
+					group.Name, len(payload), limits.MaxRuleGroupSizeBytes))
+			}
+		}
+	}
+
+	if limits.MaxRuleGroupsPerTenant > 0 {
+		declared := ruleGroupNames(groups)
+		others, kept := 0, 0
+		for remoteNamespace, remoteGroups := range tenantGroups {
+			if remoteNamespace != namespace {
+				others += len(remoteGroups)
+				continue
+			}
+			for _, group := range remoteGroups {
+				if !declared[group.Name] && owned != nil && !owned[group.Name] {
+					kept++
+				}
+			}
+		}
+		if total := others + kept + len(groups); total > limits.MaxRuleGroupsPerTenant {
+			errs = append(errs, fmt.Errorf("the tenant would have %d rule groups (%d in namespace %q and %d in the other namespaces, currently %d in total), "+
+				"the limit is %d rule groups per tenant (ruler_max_rule_groups_per_tenant)",
+				total, kept+len(groups), namespace, others, countRuleGroups(tenantGroups), limits.MaxRuleGroupsPerTenant))
+		}
+	}
+	return errs
+}
+
+func countRuleGroups(tenantGroups map[string][]rwrulefmt.RuleGroup) int {
+	count := 0
+	for _, groups := range tenantGroups {
+		count += len(groups)
+	}
+	return count
+}
+
+// checkRulerNamespaceLimits checks the groups about to be written to the
+// namespace against the limits of the tenant, the rule groups of the tenant
+// are only listed when the number of groups is limited. Violations are
+// reported as config_yaml errors, false is returned if there are any.
+func checkRulerNamespaceLimits(ctx context.Context, source *rulerLimitsSource, client mimirClientInterface, namespace string, groups []rwrulefmt.RuleGroup, owned map[string]bool, diagnostics *diag.Diagnostics) bool {
+	if source == nil {
+		return true
+	}
+	limits := source.get(ctx)
+
+	var tenantGroups map[string][]rwrulefmt.RuleGroup
+	if limits.MaxRuleGroupsPerTenant > 0 {
+		var err error
+		// An empty namespace lists the rule groups of every namespace
+		tenantGroups, err = client.ListRules(ctx, "")
+		if err != nil && !isNotFound(err) {
+			addAPIErrorDiagnostic(diagnostics, "list the rule groups of the tenant to check the ruler limits", err)
+			return false
+		}
+	}
+
+	errs := checkRulerLimits(limits, tenantGroups, namespace, groups, owned)
+	for _, err := range errs {
+		diagnostics.AddAttributeError(path.Root("config_yaml"), "Ruler Limit Exceeded", err.Error())
+	}
+	return len(errs) == 0
+}
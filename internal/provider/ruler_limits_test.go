@@ -0,0 +1,92 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grafana/mimir/pkg/mimirtool/rules/rwrulefmt"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/prometheus/prometheus/model/rulefmt"
+)
+
+func TestRulerLimitsSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != userLimitsPath || r.Header.Get("X-Scope-OrgID") != "tenant" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"ingestion_rate":10000,"ruler_max_rules_per_rule_group":20,"ruler_max_rule_groups_per_tenant":70}`))
+	}))
+	defer server.Close()
+	cfg := MimirClientConfig{Address: server.URL, TenantID: "tenant"}
+
+	limits := newRulerLimitsSource(server.Client(), cfg, nil).get(context.Background())
+	if limits != (rulerLimits{MaxRuleGroupsPerTenant: 70, MaxRulesPerRuleGroup: 20}) {
+		t.Fatalf("unexpected fetched limits: %+v", limits)
+	}
+
+	// The configured limits take precedence
+	limits = newRulerLimitsSource(server.Client(), cfg, &rulerLimitsModel{
+		MaxRulesPerRuleGroup:  types.Int64Value(0),
+		MaxRuleGroupSizeBytes: types.Int64Value(1024),
+	}).get(context.Background())
+	if limits != (rulerLimits{MaxRuleGroupsPerTenant: 70, MaxRuleGroupSizeBytes: 1024}) {
+		t.Fatalf("unexpected overridden limits: %+v", limits)
+	}
+
+	// Only the configured limits are used when the endpoint isn't available or fetch is disabled
+	for _, source := range []*rulerLimitsSource{
+		newRulerLimitsSource(server.Client(), MimirClientConfig{Address: server.URL, TenantID: "other"}, &rulerLimitsModel{MaxRulesPerRuleGroup: types.Int64Value(5)}),
+		newRulerLimitsSource(server.Client(), cfg, &rulerLimitsModel{Fetch: types.BoolValue(false), MaxRulesPerRuleGroup: types.Int64Value(5)}),
+	} {
+		if limits := source.get(context.Background()); limits != (rulerLimits{MaxRulesPerRuleGroup: 5}) {
+			t.Fatalf("unexpected configured limits: %+v", limits)
+		}
+	}
+}
+
+func testRuleGroupWithRules(name string, count int) rwrulefmt.RuleGroup {
+	group := testRuleGroup(name, 0)
+	for i := 0; i < count; i++ {
+		group.Rules = append(group.Rules, rulefmt.RuleNode{})
+	}
+	return group
+}
+
+func TestCheckRulerNamespaceLimits(t *testing.T) {
+	ctx := context.Background()
+	client := newFakeMimirClient()
+	for _, group := range []string{"a", "b"} {
+		_ = client.CreateRuleGroup(ctx, "other", testRuleGroup(group, 0))
+	}
+	_ = client.CreateRuleGroup(ctx, "ns", testRuleGroup("unmanaged", 0))
+	_ = client.CreateRuleGroup(ctx, "ns", testRuleGroup("old", 0))
+	source := &rulerLimitsSource{configured: &rulerLimitsModel{
+		MaxRuleGroupsPerTenant: types.Int64Value(4),
+		MaxRulesPerRuleGroup:   types.Int64Value(2),
+	}}
+
+	// The groups of the namespace are replaced: 2 + 2 groups
+	groups := []rwrulefmt.RuleGroup{testRuleGroupWithRules("g1", 2), testRuleGroupWithRules("g2", 1)}
+	var diags diag.Diagnostics
+	if !checkRulerNamespaceLimits(ctx, source, client, "ns", groups, nil, &diags) {
+		t.Fatalf("unexpected errors: %v", diags)
+	}
+
+	// Unowned groups are kept: 2 + 1 + 2 groups
+	if checkRulerNamespaceLimits(ctx, source, client, "ns", groups, map[string]bool{"g1": true, "g2": true, "old": true}, &diags) ||
+		diags.ErrorsCount() != 1 || !strings.Contains(diags[0].Detail(), "the tenant would have 5 rule groups (3 in namespace \"ns\" and 2 in the other namespaces, currently 4 in total)") {
+		t.Fatalf("expected the tenant limit to be exceeded, got: %v", diags)
+	}
+
+	diags = nil
+	groups = append(groups, testRuleGroupWithRules("g3", 3))
+	if checkRulerNamespaceLimits(ctx, source, client, "new", groups, nil, &diags) || diags.ErrorsCount() != 2 ||
+		!strings.Contains(diags[0].Detail(), `group "g3" has 3 rules, the limit is 2 rules per rule group`) {
+		t.Fatalf("expected the group and tenant limits to be exceeded, got: %v", diags)
+	}
+}
@@ -0,0 +1,239 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/grafana/mimir/pkg/mimirtool/rules/rwrulefmt"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// ruleGroupError is returned when writing a single rule group to Grafana Mimir fails.
+type ruleGroupError struct {
+	// Action is what was done on the group, e.g. "create" or "delete"
+	Action string
+	Group  string
+	Err    error
+}
+
+func (e *ruleGroupError) Error() string {
+	return fmt.Sprintf("failed to %s rule group %q: %s", e.Action, e.Group, e.Err)
+}
+
+func (e *ruleGroupError) Unwrap() error {
+	return e.Err
+}
+
+// Create rule groups in Mimir, the names of the groups written before a failure are returned
+func createAllRuleGroups(ctx context.Context, client mimirClientInterface, namespace string, groups []rwrulefmt.RuleGroup) ([]string, error) {
+	written := make([]string, 0, len(groups))
+	for _, group := range groups {
+		if err := client.CreateRuleGroup(ctx, namespace, group); err != nil {
+			return written, &ruleGroupError{Action: "create", Group: group.Name, Err: err}
+		}
+		written = append(written, group.Name)
+	}
+	return written, nil
+}
+
+// applyRuleGroups writes the given rule groups to the namespace and deletes the
+// remote groups that are owned but no longer declared. A nil owned set means
+// the whole namespace is owned.
+// The write is transactional at the namespace level: the remote groups are
+// captured beforehand and, if any group fails, the groups already written are
+// deleted or restored to their previous definition. Failures are reported in
+// diagnostics, false is returned when the namespace was not updated.
+func applyRuleGroups(ctx context.Context, client mimirClientInterface, namespace string, groups []rwrulefmt.RuleGroup, owned map[string]bool, diagnostics *diag.Diagnostics) bool {
+	previous, _, err := listRemoteRuleGroups(ctx, client, namespace)
+	if err != nil {
+		addAPIErrorDiagnostic(diagnostics, fmt.Sprintf("read the current rule groups of namespace %q before writing them", namespace), err)
+		return false
+	}
+
+	touched, err := createAllRuleGroups(ctx, client, namespace, groups)
+	if err == nil {
+		// Remove the groups that are no longer declared
+		declared := ruleGroupNames(groups)
+		for _, group := range previous {
+			if declared[group.Name] || (owned != nil && !owned[group.Name]) {
+				continue
+			}
+			if err = client.DeleteRuleGroup(ctx, namespace, group.Name); err != nil && !isNotFound(err) {
+				err = &ruleGroupError{Action: "delete", Group: group.Name, Err: err}
+				break
+			}
+			err = nil
+			touched = append(touched, group.Name)
+		}
+	}
+	if err == nil {
+		return true
+	}
+
+	action := fmt.Sprintf("write the rule groups of namespace %q", namespace)
+	var groupErr *ruleGroupError
+	if errors.As(err, &groupErr) {
+		action = fmt.Sprintf("%s rule group %q in namespace %q", groupErr.Action, groupErr.Group, namespace)
+	}
+	addAPIErrorDiagnostic(diagnostics, action, err)
+
+	if rollbackErrs := rollbackRuleGroups(ctx, client, namespace, previous, touched); len(rollbackErrs) > 0 {
+		addRollbackErrorDiagnostic(diagnostics, namespace, rollbackErrs)
+		return false
+	}
+	if len(touched) > 0 {
+		diagnostics.AddWarning(
+			"Rule Groups Rolled Back",
+			fmt.Sprintf("The rule groups of namespace %q were restored to their content before the apply: %s.", namespace, strings.Join(touched, ", ")),
+		)
+	}
+	return false
+}
+
+// rollbackRuleGroups restores the touched groups to their previous definition,
+// groups which didn't exist before are deleted.
+func rollbackRuleGroups(ctx context.Context, client mimirClientInterface, namespace string, previous []rwrulefmt.RuleGroup, touched []string) []error {
+	// The rollback must run even if the failure was caused by the operation deadline
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), defaultTimeout)
+	defer cancel()
+
+	previousByName := make(map[string]rwrulefmt.RuleGroup, len(previous))
+	for _, group := range previous {
+		previousByName[group.Name] = group
+	}
+
+	var errs []error
+	for _, name := range touched {
+		tflog.Info(ctx, "Rolling back rule group", map[string]interface{}{"namespace": namespace, "group": name})
+		if group, ok := previousByName[name]; ok {
+			if err := client.CreateRuleGroup(ctx, namespace, group); err != nil {
+				errs = append(errs, &ruleGroupError{Action: "restore", Group: name, Err: err})
+			}
+			continue
+		}
+		if err := client.DeleteRuleGroup(ctx, namespace, name); err != nil && !isNotFound(err) {
+			errs = append(errs, &ruleGroupError{Action: "delete", Group: name, Err: err})
+		}
+	}
+	return errs
+}
+
+// addRollbackErrorDiagnostic reports the groups of the namespace which could
+// not be restored after a failed write.
+func addRollbackErrorDiagnostic(diagnostics *diag.Diagnostics, namespace string, rollbackErrs []error) {
+	details := make([]string, 0, len(rollbackErrs))
+	for _, rollbackErr := range rollbackErrs {
+		details = append(details, "- "+rollbackErr.Error())
+	}
+	diagnostics.AddError(
+		"Failed to Roll Back Rule Groups",
+		fmt.Sprintf("Namespace %q could not be restored to its previous content after the failure above and may be partially updated:\n%s\n\n"+
+			"Run terraform apply again once the cause of the failure is fixed.", namespace, strings.Join(details, "\n")),
+	)
+}
+
+// renameNamespace moves the rule groups to a new namespace without a window
+// where they are not evaluated: the groups are created in the new namespace,
+// their presence is confirmed, and only then the previous namespace is
+// deleted, or only its owned groups when owned is not nil. false is returned
+// when the new namespace could not be populated, it is rolled back and the
+// previous one is left untouched in that case.
+func renameNamespace(ctx context.Context, client mimirClientInterface, from, to string, groups []rwrulefmt.RuleGroup, owned map[string]bool, diagnostics *diag.Diagnostics) bool {
+	var ownedInTarget map[string]bool
+	if owned != nil {
+		ownedInTarget = ruleGroupNames(groups)
+	}
+	previous, _, err := listRemoteRuleGroups(ctx, client, to)
+	if err != nil {
+		addAPIErrorDiagnostic(diagnostics, fmt.Sprintf("read the current rule groups of namespace %q before writing them", to), err)
+		return false
+	}
+	if !applyRuleGroups(ctx, client, to, groups, ownedInTarget, diagnostics) {
+		return false
+	}
+
+	remoteGroups, _, err := listRemoteRuleGroups(ctx, client, to)
+	if err != nil {
+		addAPIErrorDiagnostic(diagnostics, fmt.Sprintf("confirm the rule groups of namespace %q", to), err)
+		return false
+	}
+	present := ruleGroupNames(remoteGroups)
+	var missing []string
+	for _, group := range groups {
+		if !present[group.Name] {
+			missing = append(missing, group.Name)
+		}
+	}
+	if len(missing) > 0 {
+		diagnostics.AddError(
+			"Renamed Namespace Not Confirmed",
+			fmt.Sprintf("The rule groups %s were written to namespace %q but are not listed by Grafana Mimir. "+
+				"Namespace %q was kept to avoid interrupting the evaluation of its rules.", strings.Join(missing, ", "), to, from),
+		)
+		// The groups written, and the ones deleted when the whole namespace is owned, are restored
+		touched := make([]string, 0, len(groups)+len(previous))
+		for _, group := range groups {
+			touched = append(touched, group.Name)
+		}
+		for _, group := range previous {
+			if ownedInTarget == nil && !slices.Contains(touched, group.Name) {
+				touched = append(touched, group.Name)
+			}
+		}
+		if rollbackErrs := rollbackRuleGroups(ctx, client, to, previous, touched); len(rollbackErrs) > 0 {
+			addRollbackErrorDiagnostic(diagnostics, to, rollbackErrs)
+		}
+		return false
+	}
+
+	tflog.Info(ctx, "Deleting previous namespace after rename", map[string]interface{}{"from": from, "to": to})
+	if err := deleteOwnedRuleGroups(ctx, client, from, owned); err != nil {
+		// The new namespace is live, only report the leftover
+		addAPIErrorDiagnostic(diagnostics, fmt.Sprintf("delete the previous namespace %q after renaming it to %q, it must be deleted manually", from, to), err)
+	}
+	return true
+}
+
+// deleteOwnedRuleGroups deletes the owned rule groups of the namespace, or the
+// whole namespace when owned is nil. Groups already gone are ignored.
+func deleteOwnedRuleGroups(ctx context.Context, client mimirClientInterface, namespace string, owned map[string]bool) error {
+	if owned == nil {
+		if err := client.DeleteNamespace(ctx, namespace); err != nil && !isNotFound(err) {
+			return err
+		}
+		return nil
+	}
+	for name := range owned {
+		if err := client.DeleteRuleGroup(ctx, namespace, name); err != nil && !isNotFound(err) {
+			return &ruleGroupError{Action: "delete", Group: name, Err: err}
+		}
+	}
+	return nil
+}
+
+// ruleGroupNames returns the set of the names of the groups
+func ruleGroupNames(groups []rwrulefmt.RuleGroup) map[string]bool {
+	names := make(map[string]bool, len(groups))
+	for _, group := range groups {
+		names[group.Name] = true
+	}
+	return names
+}
+
+// filterOwnedRuleGroups keeps the owned groups, all of them when owned is nil
+func filterOwnedRuleGroups(groups []rwrulefmt.RuleGroup, owned map[string]bool) []rwrulefmt.RuleGroup {
+	if owned == nil {
+		return groups
+	}
+	filtered := make([]rwrulefmt.RuleGroup, 0, len(groups))
+	for _, group := range groups {
+		if owned[group.Name] {
+			filtered = append(filtered, group)
+		}
+	}
+	return filtered
+}
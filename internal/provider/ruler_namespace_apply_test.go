@@ -0,0 +1,197 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/grafana/mimir/pkg/mimirtool/client"
+	"github.com/grafana/mimir/pkg/mimirtool/rules/rwrulefmt"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/prometheus/prometheus/model/rulefmt"
+)
+
+// fakeMimirClient is an in-memory ruler, failCreate lists the groups whose
+// creation fails and dropOnList the groups missing from the listings.
+type fakeMimirClient struct {
+	mimirClientInterface
+	namespaces map[string]map[string]rwrulefmt.RuleGroup
+	failCreate map[string]bool
+	dropOnList map[string]bool
+}
+
+func newFakeMimirClient() *fakeMimirClient {
+	return &fakeMimirClient{namespaces: map[string]map[string]rwrulefmt.RuleGroup{}, failCreate: map[string]bool{}, dropOnList: map[string]bool{}}
+}
+
+func (c *fakeMimirClient) CreateRuleGroup(_ context.Context, namespace string, rg rwrulefmt.RuleGroup) error {
+	if c.failCreate[rg.Name] {
+		return errors.New(`server returned HTTP status: 400 Bad Request, body: "invalid group"`)
+	}
+	if c.namespaces[namespace] == nil {
+		c.namespaces[namespace] = map[string]rwrulefmt.RuleGroup{}
+	}
+	c.namespaces[namespace][rg.Name] = rg
+	return nil
+}
+
+func (c *fakeMimirClient) DeleteRuleGroup(_ context.Context, namespace, groupName string) error {
+	if _, ok := c.namespaces[namespace][groupName]; !ok {
+		return client.ErrResourceNotFound
+	}
+	delete(c.namespaces[namespace], groupName)
+	return nil
+}
+
+func (c *fakeMimirClient) DeleteNamespace(_ context.Context, namespace string) error {
+	if len(c.namespaces[namespace]) == 0 {
+		return client.ErrResourceNotFound
+	}
+	delete(c.namespaces, namespace)
+	return nil
+}
+
+func (c *fakeMimirClient) ListRules(ctx context.Context, namespace string) (map[string][]rwrulefmt.RuleGroup, error) {
+	if namespace == "" {
+		// Every namespace of the tenant
+		all := map[string][]rwrulefmt.RuleGroup{}
+		for name := range c.namespaces {
+			if groups, err := c.ListRules(ctx, name); err == nil {
+				all[name] = groups[name]
+			}
+		}
+		return all, nil
+	}
+	if len(c.namespaces[namespace]) == 0 {
+		return nil, client.ErrResourceNotFound
+	}
+	groups := make([]rwrulefmt.RuleGroup, 0, len(c.namespaces[namespace]))
+	for _, group := range c.namespaces[namespace] {
+		if !c.dropOnList[group.Name] {
+			groups = append(groups, group)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	return map[string][]rwrulefmt.RuleGroup{namespace: groups}, nil
+}
+
+func (c *fakeMimirClient) groupNames(namespace string) []string {
+	names := []string{}
+	for name := range c.namespaces[namespace] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func testRuleGroup(name string, limit int) rwrulefmt.RuleGroup {
+	return rwrulefmt.RuleGroup{RuleGroup: rulefmt.RuleGroup{Name: name, Limit: limit}}
+}
+
+func TestApplyRuleGroupsRollback(t *testing.T) {
+	ctx := context.Background()
+	cli := newFakeMimirClient()
+
+	var diags diag.Diagnostics
+	if !applyRuleGroups(ctx, cli, "demo", []rwrulefmt.RuleGroup{testRuleGroup("a", 1), testRuleGroup("b", 1)}, nil, &diags) {
+		t.Fatalf("unexpected failure: %v", diags)
+	}
+
+	// Update "a", add "c", drop "b", but "d" fails: everything must be restored
+	cli.failCreate["d"] = true
+	diags = nil
+	ok := applyRuleGroups(ctx, cli, "demo", []rwrulefmt.RuleGroup{testRuleGroup("a", 2), testRuleGroup("c", 1), testRuleGroup("d", 1)}, nil, &diags)
+	if ok {
+		t.Fatal("expected the apply to fail")
+	}
+	if diags.ErrorsCount() != 1 || !strings.Contains(diags.Errors()[0].Detail(), `rule group "d"`) || !strings.Contains(diags.Errors()[0].Detail(), "invalid group") {
+		t.Fatalf("expected a single error naming the failing group, got: %v", diags)
+	}
+	if names := cli.groupNames("demo"); strings.Join(names, ",") != "a,b" {
+		t.Fatalf("expected the previous groups to be restored, got %v", names)
+	}
+	if limit := cli.namespaces["demo"]["a"].Limit; limit != 1 {
+		t.Fatalf("expected group a to be restored to its previous definition, got limit %d", limit)
+	}
+
+	// Removing a group that is no longer declared
+	diags = nil
+	if !applyRuleGroups(ctx, cli, "demo", []rwrulefmt.RuleGroup{testRuleGroup("a", 3)}, nil, &diags) {
+		t.Fatalf("unexpected failure: %v", diags)
+	}
+	if names := cli.groupNames("demo"); strings.Join(names, ",") != "a" {
+		t.Fatalf("expected only group a to remain, got %v", names)
+	}
+}
+
+func TestRenameNamespace(t *testing.T) {
+	ctx := context.Background()
+	cli := newFakeMimirClient()
+	groups := []rwrulefmt.RuleGroup{testRuleGroup("a", 1)}
+
+	var diags diag.Diagnostics
+	if !applyRuleGroups(ctx, cli, "old", groups, nil, &diags) {
+		t.Fatalf("unexpected failure: %v", diags)
+	}
+
+	// A failing rename keeps the previous namespace
+	cli.failCreate["b"] = true
+	if renameNamespace(ctx, cli, "old", "new", append(groups, testRuleGroup("b", 1)), nil, &diags) {
+		t.Fatal("expected the rename to fail")
+	}
+	if len(cli.groupNames("old")) != 1 || len(cli.groupNames("new")) != 0 {
+		t.Fatalf("expected the previous namespace to be untouched, got old=%v new=%v", cli.groupNames("old"), cli.groupNames("new"))
+	}
+
+	// An unconfirmed rename rolls the new namespace back to its previous content
+	diags = nil
+	cli.failCreate["b"] = false
+	_ = cli.CreateRuleGroup(ctx, "new", testRuleGroup("existing", 1))
+	cli.dropOnList["b"] = true
+	if renameNamespace(ctx, cli, "old", "new", append(groups, testRuleGroup("b", 1)), nil, &diags) ||
+		diags.ErrorsCount() != 1 || diags[0].Summary() != "Renamed Namespace Not Confirmed" {
+		t.Fatalf("expected the rename not to be confirmed, got: %v", diags)
+	}
+	if strings.Join(cli.groupNames("old"), ",") != "a" || strings.Join(cli.groupNames("new"), ",") != "existing" {
+		t.Fatalf("expected the new namespace to be rolled back, got old=%v new=%v", cli.groupNames("old"), cli.groupNames("new"))
+	}
+	delete(cli.dropOnList, "b")
+	_ = cli.DeleteNamespace(ctx, "new")
+
+	diags = nil
+	if !renameNamespace(ctx, cli, "old", "new", groups, nil, &diags) || diags.HasError() {
+		t.Fatalf("unexpected failure: %v", diags)
+	}
+	if len(cli.groupNames("old")) != 0 || strings.Join(cli.groupNames("new"), ",") != "a" {
+		t.Fatalf("expected the groups to be moved, got old=%v new=%v", cli.groupNames("old"), cli.groupNames("new"))
+	}
+}
+
+func TestApplyRuleGroupsManagedGroupsOnly(t *testing.T) {
+	ctx := context.Background()
+	cli := newFakeMimirClient()
+
+	var diags diag.Diagnostics
+	// "ci" is pushed by another tool
+	if !applyRuleGroups(ctx, cli, "shared", []rwrulefmt.RuleGroup{testRuleGroup("ci", 1), testRuleGroup("a", 1)}, nil, &diags) {
+		t.Fatalf("unexpected failure: %v", diags)
+	}
+
+	// "a" is dropped from the config and must be deleted, "ci" is not owned
+	owned := map[string]bool{"a": true, "b": true}
+	if !applyRuleGroups(ctx, cli, "shared", []rwrulefmt.RuleGroup{testRuleGroup("b", 1)}, owned, &diags) {
+		t.Fatalf("unexpected failure: %v", diags)
+	}
+	if names := cli.groupNames("shared"); strings.Join(names, ",") != "b,ci" {
+		t.Fatalf("expected the unowned group to be kept, got %v", names)
+	}
+
+	if err := deleteOwnedRuleGroups(ctx, cli, "shared", map[string]bool{"b": true}); err != nil {
+		t.Fatal(err)
+	}
+	if names := cli.groupNames("shared"); strings.Join(names, ",") != "ci" {
+		t.Fatalf("expected only the unowned group to remain, got %v", names)
+	}
+}
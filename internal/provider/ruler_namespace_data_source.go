@@ -0,0 +1,131 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &RulerNamespaceDataSource{}
+
+func NewRulerNamespaceDataSource() datasource.DataSource {
+	return &RulerNamespaceDataSource{}
+}
+
+// RulerNamespaceDataSource reads the rule groups of a namespace, whether it is
+// managed by Terraform or not.
+type RulerNamespaceDataSource struct {
+	// rulerClients are the clients of the ruler API of each backend
+	rulerClients map[string]mimirClientInterface
+}
+
+// RulerNamespaceDataSourceModel describes the data source data model.
+type RulerNamespaceDataSourceModel struct {
+	ID                 types.String `tfsdk:"id"`
+	Namespace          types.String `tfsdk:"namespace"`
+	Backend            types.String `tfsdk:"backend"`
+	ConfigYAML         types.String `tfsdk:"config_yaml"`
+	GroupSourceTenants types.Map    `tfsdk:"group_source_tenants"`
+}
+
+func (d *RulerNamespaceDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_ruler_namespace"
+}
+
+func (d *RulerNamespaceDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Reads the rule groups of a ruler namespace. [Official documentation](https://grafana.com/docs/mimir/latest/references/http-api/#ruler)",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				MarkdownDescription: "hash",
+				Computed:            true,
+			},
+			"namespace": schema.StringAttribute{
+				MarkdownDescription: "The name of the namespace to read.",
+				Required:            true,
+			},
+			"backend": schema.StringAttribute{
+				MarkdownDescription: "The ruler storing the namespace: `mimir` (default), `cortex` or `loki`.",
+				Optional:            true,
+				Validators: []validator.String{
+					stringOneOfValidator{values: rulerBackends},
+				},
+			},
+			"config_yaml": schema.StringAttribute{
+				MarkdownDescription: "The namespace's groups rules definition stored in the ruler as YAML.",
+				Computed:            true,
+			},
+			"group_source_tenants": schema.MapAttribute{
+				MarkdownDescription: "The tenants queried by the federated rule groups of the namespace, by group name, to audit cross-tenant alerting.",
+				ElementType:         groupSourceTenantsType.ElemType,
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (d *RulerNamespaceDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	c, ok := req.ProviderData.(*myClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *myClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	d.rulerClients = c.rulerClients
+}
+
+func (d *RulerNamespaceDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	tflog.Debug(ctx, "READ - init")
+	var data RulerNamespaceDataSourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	backend := data.Backend.ValueString()
+	if backend == "" {
+		backend = rulerBackendMimir
+	}
+	namespace := data.Namespace.ValueString()
+
+	groups, found, err := listRemoteRuleGroups(ctx, d.rulerClients[backend], namespace)
+	if err != nil {
+		addAPIErrorDiagnostic(&resp.Diagnostics, fmt.Sprintf("read the rule groups of namespace %q", namespace), err)
+		return
+	}
+	if !found {
+		resp.Diagnostics.AddError(
+			"Namespace Not Found",
+			fmt.Sprintf("No rule groups were found in the %s ruler for namespace %q.", backend, namespace),
+		)
+		return
+	}
+
+	normalized, ok := normalizeRemoteRuleGroups(ctx, groups, backend, "READ", &resp.Diagnostics)
+	if !ok {
+		return
+	}
+	data.ConfigYAML = types.StringValue(normalized)
+
+	sourceTenants, diags := groupSourceTenants(ctx, groups)
+	resp.Diagnostics.Append(diags...)
+	data.GroupSourceTenants = sourceTenants
+	data.ID = types.StringValue(hash(namespace))
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
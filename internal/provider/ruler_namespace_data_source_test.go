@@ -0,0 +1,56 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/statecheck"
+	"github.com/hashicorp/terraform-plugin-testing/tfjsonpath"
+)
+
+func TestAccDataSourceNamespace(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataSourceNamespace,
+				ConfigStateChecks: []statecheck.StateCheck{
+					SemanticYAMLStateCheck("data.mimirtool_ruler_namespace.federated", "config_yaml", testAccDataSourceNamespaceYaml),
+					statecheck.ExpectKnownValue(
+						"data.mimirtool_ruler_namespace.federated",
+						tfjsonpath.New("group_source_tenants"),
+						knownvalue.MapExact(map[string]knownvalue.Check{
+							"federated": knownvalue.ListExact([]knownvalue.Check{
+								knownvalue.StringExact("team-a"),
+								knownvalue.StringExact("team-b"),
+							}),
+						}),
+					),
+				},
+			},
+		},
+	})
+}
+
+const testAccDataSourceNamespaceYaml = `groups:
+- name: federated
+  source_tenants:
+  - team-a
+  - team-b
+  rules:
+  - record: job:up:sum
+    expr: sum by (job) (up)
+`
+
+const testAccDataSourceNamespace = `
+resource "mimirtool_ruler_namespace" "federated" {
+  namespace   = "federated"
+  config_yaml = <<EOT
+` + testAccDataSourceNamespaceYaml + `EOT
+}
+
+data "mimirtool_ruler_namespace" "federated" {
+  namespace = mimirtool_ruler_namespace.federated.namespace
+}
+`
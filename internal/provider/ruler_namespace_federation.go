@@ -0,0 +1,86 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/grafana/dskit/tenant"
+	"github.com/grafana/mimir/pkg/mimirtool/rules"
+	"github.com/grafana/mimir/pkg/mimirtool/rules/rwrulefmt"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// federatedRulesFeature is the build information feature telling whether
+// federated rule groups are enabled (-ruler.tenant-federation.enabled)
+const federatedRulesFeature = "federated_rules"
+
+// groupSourceTenantsType is the type of the group_source_tenants attributes
+var groupSourceTenantsType = types.MapType{ElemType: types.ListType{ElemType: types.StringType}}
+
+// checkSourceTenants returns an error for each invalid or repeated tenant ID
+// in the source_tenants of the federated rule groups.
+func checkSourceTenants(ruleNamespace rules.RuleNamespace) []error {
+	var errs []error
+	for _, group := range ruleNamespace.Groups {
+		seen := map[string]bool{}
+		for _, tenantID := range group.SourceTenants {
+			if tenantID == "" {
+				errs = append(errs, fmt.Errorf("group %q: source_tenants contains an empty tenant ID", group.Name))
+				continue
+			}
+			if err := tenant.ValidTenantID(tenantID); err != nil {
+				errs = append(errs, fmt.Errorf("group %q: invalid source tenant %q: %w", group.Name, tenantID, err))
+			}
+			if seen[tenantID] {
+				errs = append(errs, fmt.Errorf("group %q: source tenant %q is repeated", group.Name, tenantID))
+			}
+			seen[tenantID] = true
+		}
+	}
+	return errs
+}
+
+// federatedRulesEnabled tells whether federated rule groups are enabled: the
+// provider federated_rules attribute takes precedence over the feature
+// reported by the startup probe. nil is returned when neither is known.
+func federatedRulesEnabled(setting types.Bool, features map[string]string) *bool {
+	if !setting.IsNull() && !setting.IsUnknown() {
+		enabled := setting.ValueBool()
+		return &enabled
+	}
+	if value, ok := features[federatedRulesFeature]; ok {
+		if enabled, err := strconv.ParseBool(value); err == nil {
+			return &enabled
+		}
+	}
+	return nil
+}
+
+// checkFederatedRuleGroups returns an error for each federated rule group
+// when federated rule groups are known to be disabled.
+func checkFederatedRuleGroups(ruleNamespace rules.RuleNamespace, enabled *bool) []error {
+	if enabled == nil || *enabled {
+		return nil
+	}
+	var errs []error
+	for _, group := range ruleNamespace.Groups {
+		if len(group.SourceTenants) > 0 {
+			errs = append(errs, fmt.Errorf("group %q sets source_tenants but federated rule groups are disabled in Grafana Mimir "+
+				"(-ruler.tenant-federation.enabled=false)", group.Name))
+		}
+	}
+	return errs
+}
+
+// groupSourceTenants returns the source tenants of the federated rule groups by group name
+func groupSourceTenants(ctx context.Context, groups []rwrulefmt.RuleGroup) (types.Map, diag.Diagnostics) {
+	sourceTenants := map[string][]string{}
+	for _, group := range groups {
+		if len(group.SourceTenants) > 0 {
+			sourceTenants[group.Name] = group.SourceTenants
+		}
+	}
+	return types.MapValueFrom(ctx, groupSourceTenantsType.ElemType, sourceTenants)
+}
@@ -0,0 +1,57 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestCheckSourceTenants(t *testing.T) {
+	ruleNamespace, err := getRuleNamespaceFromYAML(context.Background(), `groups:
+- name: federated
+  source_tenants: [team-a, team-b]
+  rules:
+  - record: job:up:sum
+    expr: sum by (job) (up)
+- name: invalid
+  source_tenants: [team-a, "team|b", "..", team-a]
+  rules:
+  - record: job:up:sum
+    expr: sum by (job) (up)
+`, rulerBackendMimir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errs := checkSourceTenants(ruleNamespace)
+	if len(errs) != 3 {
+		t.Fatalf("expected 3 errors, got %v", errs)
+	}
+	for i, expected := range []string{`"team|b"`, `".."`, `source tenant "team-a" is repeated`} {
+		if !strings.Contains(errs[i].Error(), `group "invalid"`) || !strings.Contains(errs[i].Error(), expected) {
+			t.Errorf("expected error %d to be about %s, got %q", i, expected, errs[i])
+		}
+	}
+
+	if errs := checkFederatedRuleGroups(ruleNamespace, nil); len(errs) != 0 {
+		t.Fatalf("expected no check when federation is unknown, got %v", errs)
+	}
+	enabled := federatedRulesEnabled(types.BoolNull(), map[string]string{federatedRulesFeature: "true"})
+	if errs := checkFederatedRuleGroups(ruleNamespace, enabled); len(errs) != 0 {
+		t.Fatalf("expected federated rule groups to be allowed, got %v", errs)
+	}
+	disabled := federatedRulesEnabled(types.BoolValue(false), map[string]string{federatedRulesFeature: "true"})
+	if errs := checkFederatedRuleGroups(ruleNamespace, disabled); len(errs) != 2 {
+		t.Fatalf("expected the provider setting to take precedence and both groups to be rejected, got %v", errs)
+	}
+
+	sourceTenants, diags := groupSourceTenants(context.Background(), ruleNamespace.Groups[:1])
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	if got := sourceTenants.String(); got != `{"federated":["team-a","team-b"]}` {
+		t.Fatalf("unexpected group source tenants %s", got)
+	}
+}
@@ -0,0 +1,144 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/grafana/mimir/pkg/mimirtool/rules"
+	"github.com/grafana/mimir/pkg/mimirtool/rules/rwrulefmt"
+	"gopkg.in/yaml.v3"
+)
+
+// ruleFile is a Prometheus rule file listed by config_files
+type ruleFile struct {
+	Path    string
+	Content string
+}
+
+// expandConfigFiles returns the rule files matching the paths, globs and
+// directories of config_files, in order and without duplicates. Directories
+// are walked for .yaml and .yml files. A pattern matching no file is an error
+// as it is most likely a typo.
+func expandConfigFiles(patterns []string) ([]string, error) {
+	var files []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+
+		var matched []string
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return nil, err
+			}
+			if !info.IsDir() {
+				matched = append(matched, match)
+				continue
+			}
+			err = filepath.WalkDir(match, func(path string, entry fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if ext := filepath.Ext(path); !entry.IsDir() && (ext == ".yaml" || ext == ".yml") {
+					matched = append(matched, path)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		if len(matched) == 0 {
+			return nil, fmt.Errorf("no rule file matches %q", pattern)
+		}
+
+		slices.Sort(matched)
+		for _, file := range matched {
+			if !slices.Contains(files, file) {
+				files = append(files, file)
+			}
+		}
+	}
+	return files, nil
+}
+
+// readConfigFiles reads the rule files matching the patterns of config_files
+func readConfigFiles(patterns []string) ([]ruleFile, error) {
+	paths, err := expandConfigFiles(patterns)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]ruleFile, 0, len(paths))
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, ruleFile{Path: path, Content: string(content)})
+	}
+	return files, nil
+}
+
+// mergeRuleFiles merges the rule groups of the files into a single namespace
+// YAML. Each file must be valid on its own, and a group name can only be used
+// by a single file. The SHA-256 checksums of the files are returned by path.
+func mergeRuleFiles(ctx context.Context, files []ruleFile, backend string) (string, map[string]string, error) {
+	var errs []string
+	var groups []rwrulefmt.RuleGroup
+	definedIn := map[string]string{}
+	checksums := make(map[string]string, len(files))
+	for _, file := range files {
+		checksums[file.Path] = hash(file.Content)
+
+		ruleNamespace, err := getRuleNamespaceFromRuleFile(ctx, file, backend)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", file.Path, err))
+			continue
+		}
+		for _, group := range ruleNamespace.Groups {
+			if previous, ok := definedIn[group.Name]; ok {
+				errs = append(errs, fmt.Sprintf("%s: group %q is already defined in %s", file.Path, group.Name, previous))
+				continue
+			}
+			definedIn[group.Name] = file.Path
+			groups = append(groups, group)
+		}
+	}
+	if len(errs) > 0 {
+		return "", nil, fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
+	if len(groups) == 0 {
+		return "", nil, fmt.Errorf("the rule files define no rule group")
+	}
+
+	out, err := yaml.Marshal(rules.RuleNamespace{Groups: groups})
+	if err != nil {
+		return "", nil, err
+	}
+	return string(out), checksums, nil
+}
+
+// loadConfigFiles reads and merges the rule files matching the patterns of config_files
+func loadConfigFiles(ctx context.Context, patterns []string, backend string) (string, map[string]string, error) {
+	files, err := readConfigFiles(patterns)
+	if err != nil {
+		return "", nil, err
+	}
+	return mergeRuleFiles(ctx, files, backend)
+}
+
+// getRuleNamespaceFromRuleFile parses a rule file, empty files and files
+// without groups are allowed so that placeholders can be kept in a directory.
+func getRuleNamespaceFromRuleFile(ctx context.Context, file ruleFile, backend string) (rules.RuleNamespace, error) {
+	if strings.TrimSpace(file.Content) == "" {
+		return rules.RuleNamespace{}, nil
+	}
+	return getRuleNamespaceFromYAML(ctx, file.Content, backend)
+}
@@ -0,0 +1,116 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestRuleFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestExpandConfigFiles(t *testing.T) {
+	dir := writeTestRuleFiles(t, map[string]string{
+		"api.yaml":            "",
+		"ingester.yml":        "",
+		"README.md":           "",
+		"nested/store.yaml":   "",
+		"nested/deep/x.yaml":  "",
+		"nested/notes.txt":    "",
+		"other/querier.yaml":  "",
+		"other/frontend.yaml": "",
+	})
+
+	files, err := expandConfigFiles([]string{
+		filepath.Join(dir, "other", "q*.yaml"),
+		filepath.Join(dir, "*.y*ml"),
+		filepath.Join(dir, "nested"),
+		filepath.Join(dir, "other"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, file := range files {
+		files[i], _ = filepath.Rel(dir, file)
+	}
+	expected := []string{"other/querier.yaml", "api.yaml", "ingester.yml", "nested/deep/x.yaml", "nested/store.yaml", "other/frontend.yaml"}
+	if strings.Join(files, ",") != strings.Join(expected, ",") {
+		t.Fatalf("unexpected files: %v", files)
+	}
+
+	if _, err := expandConfigFiles([]string{filepath.Join(dir, "missing*.yaml")}); err == nil || !strings.Contains(err.Error(), "no rule file matches") {
+		t.Fatalf("expected a pattern matching nothing to be rejected, got %v", err)
+	}
+}
+
+func TestLoadConfigFiles(t *testing.T) {
+	dir := writeTestRuleFiles(t, map[string]string{
+		"a.yaml": `groups:
+- name: api
+  rules:
+  - record: job:up:sum
+    expr: sum by (job) (up)
+`,
+		"b.yaml": `groups:
+- name: ingester
+  rules:
+  - alert: IngesterDown
+    expr: up{job="ingester"} == 0
+`,
+		"empty.yaml": "",
+		"dup.yaml": `groups:
+- name: api
+  rules:
+  - record: job:up:count
+    expr: count by (job) (up)
+`,
+		"invalid.yaml": `groups:
+- name: broken
+  rules:
+  - record: job:up:count
+    expr: count by (job (up)
+`,
+	})
+	ctx := context.Background()
+
+	configYAML, checksums, err := loadConfigFiles(ctx, []string{filepath.Join(dir, "a.yaml"), filepath.Join(dir, "b.yaml"), filepath.Join(dir, "empty.yaml")}, rulerBackendMimir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ruleNamespace, err := getRuleNamespaceFromYAML(ctx, configYAML, rulerBackendMimir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ruleNamespace.Groups) != 2 || ruleNamespace.Groups[0].Name != "api" || ruleNamespace.Groups[1].Name != "ingester" {
+		t.Fatalf("unexpected merged groups:\n%s", configYAML)
+	}
+	if len(checksums) != 3 || checksums[filepath.Join(dir, "empty.yaml")] != hash("") {
+		t.Fatalf("unexpected checksums: %v", checksums)
+	}
+
+	_, _, err = loadConfigFiles(ctx, []string{filepath.Join(dir, "a.yaml"), filepath.Join(dir, "dup.yaml"), filepath.Join(dir, "invalid.yaml")}, rulerBackendMimir)
+	if err == nil {
+		t.Fatal("expected the duplicate group and the invalid file to be rejected")
+	}
+	for _, expected := range []string{
+		filepath.Join(dir, "dup.yaml") + `: group "api" is already defined in ` + filepath.Join(dir, "a.yaml"),
+		filepath.Join(dir, "invalid.yaml") + ": failed to parse namespace definition",
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q in:\n%s", expected, err)
+		}
+	}
+}
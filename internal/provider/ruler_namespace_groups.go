@@ -0,0 +1,25 @@
+package provider
+
+import (
+	"fmt"
+
+	"github.com/grafana/mimir/pkg/mimirtool/rules"
+)
+
+// checkRuleGroupOptions returns an error for each invalid rule group option.
+// The durations are already checked when the YAML is decoded, and the rules
+// by rules.RuleNamespace.Validate.
+func checkRuleGroupOptions(ruleNamespace rules.RuleNamespace) []error {
+	var errs []error
+	for _, group := range ruleNamespace.Groups {
+		if group.EvaluationDelay != nil && group.QueryOffset != nil {
+			errs = append(errs, fmt.Errorf("group %q sets both \"evaluation_delay\" and \"query_offset\", "+
+				"evaluation_delay is the deprecated name of query_offset: set only query_offset", group.Name))
+		}
+		if group.Limit < 0 {
+			errs = append(errs, fmt.Errorf("group %q has a negative \"limit\" (%d), "+
+				"it must be the maximum number of alerts or series produced by a rule, or 0 for no limit", group.Name, group.Limit))
+		}
+	}
+	return errs
+}
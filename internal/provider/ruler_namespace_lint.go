@@ -0,0 +1,117 @@
+package provider
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/grafana/mimir/pkg/mimirtool/rules"
+	"github.com/prometheus/prometheus/promql/parser"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	lintModeOff  = "off"
+	lintModeWarn = "warn"
+	lintModeFail = "fail"
+)
+
+// expressionIssue is a rule expression that doesn't parse, or whose canonical
+// formatting differs from what was written when Err is nil.
+type expressionIssue struct {
+	Group string
+	// Rule is the record or alert name of the rule
+	Rule string
+	// Line and Column are the position of the issue in config_yaml
+	Line   int
+	Column int
+	Expr   string
+	// Formatted is the canonical formatting of the expression, empty if it doesn't parse
+	Formatted string
+	Err       error
+}
+
+func (i expressionIssue) String() string {
+	if i.Err != nil {
+		return fmt.Sprintf("line %d, column %d: rule %q in group %q has an invalid expression: %s", i.Line, i.Column, i.Rule, i.Group, i.Err)
+	}
+	return fmt.Sprintf("line %d, column %d: the expression of rule %q in group %q is not canonically formatted.\n\nWritten:\n%s\n\nCanonical:\n%s",
+		i.Line, i.Column, i.Rule, i.Group, i.Expr, i.Formatted)
+}
+
+// lintRuleExpressions parses the PromQL expressions of the namespace and
+// returns the ones that are invalid or not canonically formatted, the same way
+// rules.RuleNamespace.LintExpressions does. Nothing is returned when the YAML
+// itself can't be decoded, which is reported by getRuleNamespaceFromYAML.
+func lintRuleExpressions(configYAML string) []expressionIssue {
+	decoder := yaml.NewDecoder(bytes.NewReader([]byte(configYAML)))
+	decoder.KnownFields(true)
+	var ruleNamespace rules.RuleNamespace
+	if err := decoder.Decode(&ruleNamespace); err != nil {
+		return nil
+	}
+
+	lines := strings.Split(configYAML, "\n")
+	var issues []expressionIssue
+	for _, group := range ruleNamespace.Groups {
+		for _, rule := range group.Rules {
+			if rule.Expr.Value == "" {
+				// Reported by the rule validation
+				continue
+			}
+			issue := expressionIssue{
+				Group:  group.Name,
+				Rule:   ruleName(rule),
+				Line:   rule.Expr.Line,
+				Column: rule.Expr.Column,
+				Expr:   rule.Expr.Value,
+			}
+
+			expr, err := parser.ParseExpr(rule.Expr.Value)
+			if err != nil {
+				issue.Err = err
+				var parseErrs parser.ParseErrors
+				if errors.As(err, &parseErrs) && len(parseErrs) > 0 {
+					issue.Line, issue.Column = expressionPosition(rule.Expr, lines, int(parseErrs[0].PositionRange.Start))
+					issue.Err = parseErrs[0].Err
+				}
+				issues = append(issues, issue)
+				continue
+			}
+			if formatted := expr.String(); formatted != rule.Expr.Value {
+				issue.Formatted = formatted
+				issues = append(issues, issue)
+			}
+		}
+	}
+	return issues
+}
+
+// expressionPosition translates an offset in the expression of a YAML node to
+// a line and column in the YAML document. Styles where the offset can't be
+// mapped reliably (e.g. folded scalars) fall back to the position of the node.
+func expressionPosition(node yaml.Node, lines []string, offset int) (int, int) {
+	if offset < 0 || offset > len(node.Value) {
+		return node.Line, node.Column
+	}
+	before := node.Value[:offset]
+	exprColumn := offset - strings.LastIndex(before, "\n") - 1
+
+	switch node.Style {
+	case 0:
+		// Plain scalars fold line breaks, only single line ones map directly
+		if node.Line <= len(lines) && node.Column <= len(lines[node.Line-1]) &&
+			strings.HasPrefix(lines[node.Line-1][node.Column-1:], node.Value) {
+			return node.Line, node.Column + exprColumn
+		}
+	case yaml.LiteralStyle:
+		// The content starts on the line after the indicator, with the indentation of its first line
+		if node.Line < len(lines) {
+			first := lines[node.Line]
+			indent := len(first) - len(strings.TrimLeft(first, " "))
+			return node.Line + 1 + strings.Count(before, "\n"), indent + 1 + exprColumn
+		}
+	}
+	return node.Line, node.Column
+}
@@ -0,0 +1,205 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/grafana/mimir/pkg/mimirtool/rules"
+	"github.com/grafana/mimir/pkg/mimirtool/rules/rwrulefmt"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/rulefmt"
+	"github.com/prometheus/prometheus/promql/parser"
+	"gopkg.in/yaml.v3"
+)
+
+// prepareRuleNamespace rewrites the expressions of the namespace the same way
+// `mimirtool rules prepare` does: the aggregation label is added to the `by`
+// clause of every aggregation and to the `on` clause of binary operations.
+// The expressions of the ignored rules, by record or alert name, are left as is.
+func prepareRuleNamespace(ruleNamespace rules.RuleNamespace, label string, ignored map[string]bool) error {
+	_, _, err := ruleNamespace.AggregateBy(label, func(_ rwrulefmt.RuleGroup, rule rulefmt.RuleNode) bool {
+		return !ignored[rule.Record.Value] && !ignored[rule.Alert.Value]
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add the aggregation label %q to the expressions: %w", label, err)
+	}
+	return nil
+}
+
+// injectSelectorMatchers adds the matchers to every vector selector of the
+// expressions, so that the same rules can be scoped to e.g. one cluster. A
+// selector which already has a matcher on the same label is left as is if the
+// matcher is identical, otherwise an error is returned for the rule.
+func injectSelectorMatchers(ruleNamespace rules.RuleNamespace, matchers []*labels.Matcher) []error {
+	var errs []error
+	for i, group := range ruleNamespace.Groups {
+		for j, rule := range group.Rules {
+			expr, err := parser.ParseExpr(rule.Expr.Value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("group %q, rule %q: %w", group.Name, ruleName(rule), err))
+				continue
+			}
+
+			var conflicts []string
+			parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+				selector, ok := node.(*parser.VectorSelector)
+				if !ok {
+					return nil
+				}
+				for _, matcher := range matchers {
+					conflict := ""
+					found := false
+					for _, existing := range selector.LabelMatchers {
+						if existing.Name != matcher.Name {
+							continue
+						}
+						found = true
+						if existing.Type != matcher.Type || existing.Value != matcher.Value {
+							conflict = existing.String()
+						}
+					}
+					if conflict != "" {
+						conflicts = append(conflicts, fmt.Sprintf("%s has %s which conflicts with %s", selector, conflict, matcher))
+					}
+					if !found {
+						selector.LabelMatchers = append(selector.LabelMatchers, matcher)
+					}
+				}
+				return nil
+			})
+			if len(conflicts) > 0 {
+				errs = append(errs, fmt.Errorf("group %q, rule %q: %s", group.Name, ruleName(rule), strings.Join(conflicts, ", ")))
+				continue
+			}
+			ruleNamespace.Groups[i].Rules[j].Expr.Value = expr.String()
+		}
+	}
+	return errs
+}
+
+// parseSelectorMatchers parses the selector_matchers attribute, e.g. {cluster="prod-eu"}
+func parseSelectorMatchers(selector string) ([]*labels.Matcher, error) {
+	matchers, err := parser.ParseMetricSelector(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector %q: %w", selector, err)
+	}
+	return matchers, nil
+}
+
+func ruleName(rule rulefmt.RuleNode) string {
+	if rule.Record.Value != "" {
+		return rule.Record.Value
+	}
+	return rule.Alert.Value
+}
+
+// mergeRuleLabels merges labels into every rule and annotations into every
+// alerting rule, recording rules don't support annotations. The labels and
+// annotations set on the rules take precedence over the merged ones.
+func mergeRuleLabels(ruleNamespace rules.RuleNamespace, labels, annotations map[string]string) {
+	for i, group := range ruleNamespace.Groups {
+		for j, rule := range group.Rules {
+			ruleNamespace.Groups[i].Rules[j].Labels = mergeStringMaps(labels, rule.Labels)
+			if rule.Alert.Value != "" {
+				ruleNamespace.Groups[i].Rules[j].Annotations = mergeStringMaps(annotations, rule.Annotations)
+			}
+		}
+	}
+}
+
+// mergeStringMaps returns the union of the maps, values of overrides take
+// precedence. nil is returned when both are empty to keep the YAML unchanged.
+func mergeStringMaps(base, overrides map[string]string) map[string]string {
+	if len(base) == 0 {
+		return overrides
+	}
+	merged := make(map[string]string, len(base)+len(overrides))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range overrides {
+		merged[k] = v
+	}
+	return merged
+}
+
+// stringMapValue converts a map attribute, unknown and null values are empty
+func stringMapValue(value types.Map) map[string]string {
+	values := make(map[string]string, len(value.Elements()))
+	for k, element := range value.Elements() {
+		if v, ok := element.(types.String); ok && !v.IsUnknown() && !v.IsNull() {
+			values[k] = v.ValueString()
+		}
+	}
+	return values
+}
+
+// ruleLabels returns the labels merged into every rule: extra_labels override
+// the provider default_rule_labels.
+func (m RulerNamespaceResourceModel) ruleLabels(defaultLabels map[string]string) map[string]string {
+	return mergeStringMaps(defaultLabels, stringMapValue(m.ExtraLabels))
+}
+
+// cloneRuleNamespace copies the groups and rules of the namespace, so that they
+// can be rewritten without changing the namespace they come from.
+func cloneRuleNamespace(ruleNamespace rules.RuleNamespace) rules.RuleNamespace {
+	clone := ruleNamespace
+	clone.Groups = slices.Clone(ruleNamespace.Groups)
+	for i, group := range clone.Groups {
+		clone.Groups[i].Rules = slices.Clone(group.Rules)
+	}
+	return clone
+}
+
+// prepare returns the rule groups as written to Grafana Mimir, with the
+// rewrites configured on the resource applied: the selector matchers are
+// injected first, then the aggregation label is added, and finally the labels
+// and annotations are merged into the rules. ruleNamespace is left unchanged.
+func (m RulerNamespaceResourceModel) prepare(ruleNamespace rules.RuleNamespace, defaultLabels map[string]string) (rules.RuleNamespace, error) {
+	ruleNamespace = cloneRuleNamespace(ruleNamespace)
+	if m.SelectorMatchers.ValueString() != "" {
+		matchers, err := parseSelectorMatchers(m.SelectorMatchers.ValueString())
+		if err != nil {
+			return rules.RuleNamespace{}, err
+		}
+		if errs := injectSelectorMatchers(ruleNamespace, matchers); len(errs) > 0 {
+			return rules.RuleNamespace{}, fmt.Errorf("failed to inject the selector matchers: %w", errors.Join(errs...))
+		}
+	}
+
+	if m.PrepareAggregationLabel.ValueString() != "" {
+		ignored := map[string]bool{}
+		for _, element := range m.PrepareIgnoredRules.Elements() {
+			if name, ok := element.(types.String); ok {
+				ignored[name.ValueString()] = true
+			}
+		}
+		if err := prepareRuleNamespace(ruleNamespace, m.PrepareAggregationLabel.ValueString(), ignored); err != nil {
+			return rules.RuleNamespace{}, err
+		}
+	}
+
+	mergeRuleLabels(ruleNamespace, m.ruleLabels(defaultLabels), stringMapValue(m.ExtraAnnotations))
+	return ruleNamespace, nil
+}
+
+// preparedConfigYAML returns the prepared rule groups, the ones to write to
+// Grafana Mimir, and their YAML which is null when nothing is rewritten.
+func (m RulerNamespaceResourceModel) preparedConfigYAML(ruleNamespace rules.RuleNamespace, defaultLabels map[string]string) (rules.RuleNamespace, types.String, error) {
+	if m.PrepareAggregationLabel.ValueString() == "" && m.SelectorMatchers.ValueString() == "" &&
+		len(m.ruleLabels(defaultLabels)) == 0 && len(stringMapValue(m.ExtraAnnotations)) == 0 {
+		return ruleNamespace, types.StringNull(), nil
+	}
+	prepared, err := m.prepare(ruleNamespace, defaultLabels)
+	if err != nil {
+		return rules.RuleNamespace{}, types.StringNull(), err
+	}
+	out, err := yaml.Marshal(prepared)
+	if err != nil {
+		return rules.RuleNamespace{}, types.StringNull(), err
+	}
+	return prepared, types.StringValue(string(out)), nil
+}
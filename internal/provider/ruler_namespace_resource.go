@@ -5,14 +5,17 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/grafana/mimir/pkg/mimirtool/client"
 	"github.com/grafana/mimir/pkg/mimirtool/rules"
 	"github.com/grafana/mimir/pkg/mimirtool/rules/rwrulefmt"
+	goversion "github.com/hashicorp/go-version"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
@@ -22,8 +25,10 @@ import (
 
 // Ensure provider defined types fully satisfy framework interfaces.
 var (
-	_ resource.Resource                = &RulerNamespaceResource{}
-	_ resource.ResourceWithImportState = &RulerNamespaceResource{}
+	_ resource.Resource                   = &RulerNamespaceResource{}
+	_ resource.ResourceWithImportState    = &RulerNamespaceResource{}
+	_ resource.ResourceWithModifyPlan     = &RulerNamespaceResource{}
+	_ resource.ResourceWithValidateConfig = &RulerNamespaceResource{}
 )
 
 func NewRulerNamespaceResource() resource.Resource {
@@ -32,17 +37,46 @@ func NewRulerNamespaceResource() resource.Resource {
 
 // RulerNamespaceResource defines the resource implementation.
 type RulerNamespaceResource struct {
-	client *client.MimirClient
+	// rulerClients are the clients of the ruler API of each backend
+	rulerClients map[string]mimirClientInterface
+	// mimirVersion is the Grafana Mimir version reported by the provider startup probe, nil when unknown
+	mimirVersion *goversion.Version
+	// federatedRules tells whether federated rule groups are enabled, nil when unknown
+	federatedRules *bool
+	// unsupportedRuleGroupFields is how fields unsupported by mimirVersion are reported, warn or fail
+	unsupportedRuleGroupFields string
+	// defaultRuleLabels are the labels merged into every rule, from the provider configuration
+	defaultRuleLabels map[string]string
+	// rulePolicy is the provider rule policy, nil when not set
+	rulePolicy *rulePolicyModel
+	// rulerLimits are the limits of the tenant, nil when the provider is not configured
+	rulerLimits *rulerLimitsSource
 }
 
 // RulerNamespaceResourceModel describes the resource data model.
 type RulerNamespaceResourceModel struct {
-	ID                       types.String `tfsdk:"id"`
-	Namespace                types.String `tfsdk:"namespace"`
-	ConfigYAML               types.String `tfsdk:"config_yaml"`
-	RemoteConfigYAML         types.String `tfsdk:"remote_config_yaml"`
-	StrictRecordingRuleCheck types.Bool   `tfsdk:"strict_recording_rule_check"`
-	RecordingRuleCheck       types.Bool   `tfsdk:"recording_rule_check"`
+	ID                       types.String     `tfsdk:"id"`
+	Namespace                types.String     `tfsdk:"namespace"`
+	ConfigYAML               types.String     `tfsdk:"config_yaml"`
+	ConfigFiles              types.List       `tfsdk:"config_files"`
+	ConfigFileChecksums      types.Map        `tfsdk:"config_file_checksums"`
+	Backend                  types.String     `tfsdk:"backend"`
+	RemoteConfigYAML         types.String     `tfsdk:"remote_config_yaml"`
+	StrictRecordingRuleCheck types.Bool       `tfsdk:"strict_recording_rule_check"`
+	RecordingRuleCheck       types.Bool       `tfsdk:"recording_rule_check"`
+	AllowOverwrite           types.Bool       `tfsdk:"allow_overwrite"`
+	ManagedGroupsOnly        types.Bool       `tfsdk:"managed_groups_only"`
+	LintMode                 types.String     `tfsdk:"lint_mode"`
+	PrepareAggregationLabel  types.String     `tfsdk:"prepare_aggregation_label"`
+	PrepareIgnoredRules      types.Set        `tfsdk:"prepare_ignored_rules"`
+	SelectorMatchers         types.String     `tfsdk:"selector_matchers"`
+	ExtraLabels              types.Map        `tfsdk:"extra_labels"`
+	ExtraAnnotations         types.Map        `tfsdk:"extra_annotations"`
+	TestsYAML                types.String     `tfsdk:"tests_yaml"`
+	RulePolicy               *rulePolicyModel `tfsdk:"rule_policy"`
+	PreparedConfigYAML       types.String     `tfsdk:"prepared_config_yaml"`
+	GroupSourceTenants       types.Map        `tfsdk:"group_source_tenants"`
+	Timeouts                 timeouts.Value   `tfsdk:"timeouts"`
 }
 
 func (r *RulerNamespaceResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -65,21 +99,49 @@ func (r *RulerNamespaceResource) Schema(ctx context.Context, _ resource.SchemaRe
 				},
 			},
 			"namespace": schema.StringAttribute{
-				MarkdownDescription: "The name of the namespace to create in Grafana Mimir.",
+				MarkdownDescription: "The name of the namespace to create in Grafana Mimir. Renaming is done in place: the rule groups are created in the new namespace before the previous one is deleted.",
 				Required:            true,
-				// Ensures that Terraform destroys and recreates the resource when the namespace changes
-				// as the hash(namespace) will change
-				PlanModifiers: []planmodifier.String{
-					stringplanmodifier.RequiresReplace(),
-				},
 			},
 			"config_yaml": schema.StringAttribute{
-				MarkdownDescription: "User supplied namespace's groups rules definition to create in Grafana Mimir as YAML.",
-				Required:            true,
+				MarkdownDescription: "User supplied namespace's groups rules definition to create in Grafana Mimir as YAML. " +
+					"Exactly one of `config_yaml` and `config_files` must be set, it holds the merged rule groups of `config_files` otherwise.",
+				Optional: true,
+				Computed: true,
 				Validators: []validator.String{
 					namespaceYAMLValidator{},
 				},
 			},
+			"config_files": schema.ListAttribute{
+				MarkdownDescription: "Paths, globs (e.g. `${path.module}/rules/*.yaml`) or directories of Prometheus rule files whose rule groups are merged into the namespace. " +
+					"Directories are walked for `.yaml` and `.yml` files. Each file is validated on its own, so that issues are reported with their position in the file, " +
+					"and a group name can only be used by a single file. Exactly one of `config_yaml` and `config_files` must be set.",
+				ElementType: types.StringType,
+				Optional:    true,
+				Validators: []validator.List{
+					configFilesValidator{},
+				},
+			},
+			"config_file_checksums": schema.MapAttribute{
+				MarkdownDescription: "The SHA-256 checksums of the rule files of `config_files` by path, to show in plans which file changed.",
+				ElementType:         types.StringType,
+				Computed:            true,
+			},
+			"backend": schema.StringAttribute{
+				MarkdownDescription: "The ruler storing the namespace: `mimir` (default), `cortex` or `loki`. " +
+					"Cortex and Loki are reached on their `/api/v1/rules` and `/loki/api/v1/rules` routes. " +
+					"The expressions of Loki rules are LogQL: the provider only checks their brackets, strings and stream selectors, the complete expressions are validated by Loki when written. The PromQL specific " +
+					"`lint_mode`, `prepare_aggregation_label`, `selector_matchers` and `tests_yaml` don't apply to them. " +
+					"Changing it forces a new resource.",
+				Optional: true,
+				Computed: true,
+				Default:  stringdefault.StaticString(rulerBackendMimir),
+				PlanModifiers: []planmodifier.String{
+					backendRequiresReplace(),
+				},
+				Validators: []validator.String{
+					stringOneOfValidator{values: rulerBackends},
+				},
+			},
 			"remote_config_yaml": schema.StringAttribute{
 				MarkdownDescription: "The namespace's groups rules definition stored in Grafana Mimir as YAML.",
 				Optional:            true,
@@ -97,6 +159,94 @@ func (r *RulerNamespaceResource) Schema(ctx context.Context, _ resource.SchemaRe
 				Default:             booldefault.StaticBool(true),
 				Computed:            true, // see above
 			},
+			"allow_overwrite": schema.BoolAttribute{
+				MarkdownDescription: "Allow creating the resource over a namespace that already contains rule groups in Grafana Mimir, replacing them. When false, creation fails if the namespace exists; use `terraform import` to adopt it instead.",
+				Optional:            true,
+				Default:             booldefault.StaticBool(false),
+				Computed:            true, // see above
+			},
+			"managed_groups_only": schema.BoolAttribute{
+				MarkdownDescription: "Only manage the rule groups declared in `config_yaml`, to share the namespace with groups pushed by other tools. " +
+					"Other groups are left untouched on update and delete, and ignored in `remote_config_yaml`.",
+				Optional: true,
+				Default:  booldefault.StaticBool(false),
+				Computed: true, // see above
+			},
+			"lint_mode": schema.StringAttribute{
+				MarkdownDescription: "How to report PromQL expressions of `config_yaml` whose canonical formatting differs from what was written, during `terraform validate`: " +
+					"`off` (default), `warn` or `fail`. Invalid expressions are always reported as errors.",
+				Optional: true,
+				Computed: true,
+				Default:  stringdefault.StaticString(lintModeOff),
+				Validators: []validator.String{
+					stringOneOfValidator{values: []string{lintModeOff, lintModeWarn, lintModeFail}},
+				},
+			},
+			"prepare_aggregation_label": schema.StringAttribute{
+				MarkdownDescription: "Label added to the `by` clause of every aggregation and to the `on` clause of binary operations before the rules are written, " +
+					"like `mimirtool rules prepare` does, e.g. `cluster` in multi-cluster setups. The rewritten rules are shown in `prepared_config_yaml`.",
+				Optional: true,
+				Validators: []validator.String{
+					labelNameValidator{},
+					promQLBackendValidator{},
+				},
+			},
+			"prepare_ignored_rules": schema.SetAttribute{
+				MarkdownDescription: "Names of the rules (`record` or `alert`) whose expressions are not rewritten with `prepare_aggregation_label`.",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"selector_matchers": schema.StringAttribute{
+				MarkdownDescription: "Label matchers added to every vector selector of the expressions before the rules are written, e.g. `{cluster=\"prod-eu\"}`, " +
+					"to instantiate the same rules once per cluster. Expressions with a different matcher on one of these labels are rejected. " +
+					"The rewritten rules are shown in `prepared_config_yaml`.",
+				Optional: true,
+				Validators: []validator.String{
+					selectorMatchersValidator{},
+					promQLBackendValidator{},
+				},
+			},
+			"extra_labels": schema.MapAttribute{
+				MarkdownDescription: "Labels merged into every alerting and recording rule before the rules are written. " +
+					"Labels set on a rule take precedence over `extra_labels`, which take precedence over the provider `default_rule_labels`.",
+				ElementType: types.StringType,
+				Optional:    true,
+				Validators: []validator.Map{
+					labelNamesMapValidator{},
+				},
+			},
+			"extra_annotations": schema.MapAttribute{
+				MarkdownDescription: "Annotations merged into every alerting rule before the rules are written, annotations set on a rule take precedence.",
+				ElementType:         types.StringType,
+				Optional:            true,
+				Validators: []validator.Map{
+					labelNamesMapValidator{},
+				},
+			},
+			"tests_yaml": schema.StringAttribute{
+				MarkdownDescription: "Rule unit tests in the promtool format (`input_series`, `alert_rule_test`, `promql_expr_test`...), " +
+					"run during plan against the rule groups as written to Grafana Mimir with an in-memory PromQL engine. " +
+					"`rule_files` is ignored. A failing test blocks the apply.",
+				Optional: true,
+				Validators: []validator.String{
+					ruleTestsValidator{},
+					promQLBackendValidator{},
+				},
+			},
+			"prepared_config_yaml": schema.StringAttribute{
+				MarkdownDescription: "The rule groups written to Grafana Mimir after the rewrites done with `selector_matchers`, `prepare_aggregation_label`, " +
+					"`extra_labels`, `extra_annotations` and the provider `default_rule_labels`, null when none is set.",
+				Computed: true,
+			},
+			"group_source_tenants": schema.MapAttribute{
+				MarkdownDescription: "The tenants queried by the federated rule groups of the namespace, by group name, to audit cross-tenant alerting.",
+				ElementType:         groupSourceTenantsType.ElemType,
+				Computed:            true,
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"rule_policy": rulePolicyResourceBlock(),
+			"timeouts":    timeouts.Block(ctx, timeoutsOpts),
 		},
 	}
 }
@@ -112,18 +262,178 @@ func (r *RulerNamespaceResource) Configure(ctx context.Context, req resource.Con
 		"provider_data": req.ProviderData,
 	})
 
-	client, ok := req.ProviderData.(*client.MimirClient)
+	c, ok := req.ProviderData.(*myClient)
 
 	if !ok {
 		resp.Diagnostics.AddError(
 			"Unexpected Resource Configure Type",
-			fmt.Sprintf("Expected *client.MimirClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+			fmt.Sprintf("Expected *myClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
 		)
 
 		return
 	}
 
-	r.client = client
+	r.rulerClients = c.rulerClients
+	r.mimirVersion = c.mimirVersion
+	r.federatedRules = c.federatedRules
+	r.unsupportedRuleGroupFields = c.unsupportedRuleGroupFields
+	r.defaultRuleLabels = c.defaultRuleLabels
+	r.rulePolicy = c.rulePolicy
+	r.rulerLimits = c.rulerLimits
+}
+
+// ValidateConfig requires exactly one of config_yaml and config_files.
+func (r *RulerNamespaceResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var configYAML types.String
+	var configFiles types.List
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("config_yaml"), &configYAML)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("config_files"), &configFiles)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if configYAML.IsNull() == configFiles.IsNull() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("config_yaml"),
+			"Invalid Attribute Combination",
+			"Exactly one of \"config_yaml\" and \"config_files\" must be set.",
+		)
+	}
+}
+
+// ModifyPlan handles namespace renames and runs the checks that depend on the
+// connected Grafana Mimir, the static ones are done by the attribute validators.
+func (r *RulerNamespaceResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		// Nothing to check on destroy
+		return
+	}
+
+	var plan RulerNamespaceResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var state RulerNamespaceResourceModel
+	if !req.State.Raw.IsNull() {
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		// The ID is derived from the namespace name, it changes on rename
+		if !plan.Namespace.Equal(state.Namespace) {
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("id"), types.StringUnknown())...)
+		}
+	}
+
+	backend := plan.rulerBackend()
+	if !plan.ConfigFiles.IsNull() {
+		if plan.ConfigFiles.IsUnknown() {
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("config_file_checksums"), types.MapUnknown(types.StringType))...)
+			return
+		}
+		// The rule files are read at plan time, config_yaml holds their merged groups
+		var patterns []string
+		resp.Diagnostics.Append(plan.ConfigFiles.ElementsAs(ctx, &patterns, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		configYAML, checksums, err := loadConfigFiles(ctx, patterns, backend)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("config_files"), "Invalid rule files", err.Error())
+			return
+		}
+		plan.ConfigYAML = types.StringValue(configYAML)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("config_yaml"), plan.ConfigYAML)...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("config_file_checksums"), typeMapFromMapString(checksums))...)
+	} else {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("config_file_checksums"), types.MapNull(types.StringType))...)
+	}
+
+	if plan.ConfigYAML.IsUnknown() || plan.ConfigYAML.IsNull() {
+		return
+	}
+
+	ruleNamespace, err := getRuleNamespaceFromYAML(ctx, plan.ConfigYAML.ValueString(), backend)
+	if err != nil {
+		// Reported by the validator
+		return
+	}
+
+	// The probed version is the one of Grafana Mimir
+	if backend == rulerBackendMimir {
+		for _, err := range checkRuleGroupFieldsSupport(ruleNamespace, r.mimirVersion) {
+			if r.unsupportedRuleGroupFields == unsupportedRuleGroupFieldsWarn {
+				resp.Diagnostics.AddAttributeWarning(path.Root("config_yaml"), "Unsupported rule group field", err.Error())
+			} else {
+				resp.Diagnostics.AddAttributeError(path.Root("config_yaml"), "Unsupported rule group field", err.Error())
+			}
+		}
+		for _, warning := range checkDeprecatedRuleGroupFields(ruleNamespace, r.mimirVersion) {
+			resp.Diagnostics.AddAttributeWarning(path.Root("config_yaml"), "Deprecated rule group field", warning)
+		}
+		for _, err := range checkFederatedRuleGroups(ruleNamespace, r.federatedRules) {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("config_yaml"),
+				"Federated Rule Groups Disabled",
+				err.Error(),
+			)
+		}
+	}
+
+	sourceTenants, diags := groupSourceTenants(ctx, ruleNamespace.Groups)
+	resp.Diagnostics.Append(diags...)
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("group_source_tenants"), sourceTenants)...)
+
+	// Show the rewritten rules in the plan rather than as a difference after apply
+	prepared := !plan.PrepareAggregationLabel.IsUnknown() && !plan.PrepareIgnoredRules.IsUnknown() && !plan.SelectorMatchers.IsUnknown() &&
+		!plan.ExtraLabels.IsUnknown() && !plan.ExtraAnnotations.IsUnknown()
+	if prepared {
+		preparedNamespace, preparedConfigYAML, err := plan.preparedConfigYAML(ruleNamespace, r.defaultRuleLabels)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("prepare_aggregation_label"), "Failed to prepare rules", err.Error())
+			return
+		}
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("prepared_config_yaml"), preparedConfigYAML)...)
+
+		// Catch the limits an apply would hit half-way, only when the rule groups are written
+		changed := req.State.Raw.IsNull() || !plan.Namespace.Equal(state.Namespace) || !plan.ConfigYAML.Equal(state.ConfigYAML) ||
+			!preparedConfigYAML.Equal(state.PreparedConfigYAML)
+		if changed && backend == rulerBackendMimir {
+			owned := plan.ownedRuleGroups(ctx)
+			if owned != nil {
+				for name := range declaredRuleGroupNames(ctx, state.ConfigYAML, state.rulerBackend()) {
+					owned[name] = true
+				}
+			}
+			checkRulerNamespaceLimits(ctx, r.rulerLimits, r.rulerClients[backend], plan.Namespace.ValueString(), preparedNamespace.Groups, owned, &resp.Diagnostics)
+		}
+
+		// The tests run against the rules as written
+		if usesPromQL(backend) && !plan.TestsYAML.IsUnknown() && !plan.TestsYAML.IsNull() {
+			for _, err := range runRuleTests(preparedNamespace, plan.TestsYAML.ValueString()) {
+				resp.Diagnostics.AddAttributeError(path.Root("tests_yaml"), "Rule Unit Test Failed", err.Error())
+			}
+		}
+
+		// Both rule policies check the rules as written, with the labels and annotations merged
+		plan.checkRulePolicies(preparedNamespace, r.rulePolicy, &resp.Diagnostics)
+	}
+}
+
+// checkRulePolicies checks the prepared rules against the provider settings
+// the rule policy of the resource doesn't override, and against the attributes
+// of the resource policy depending on the labels, the other ones are checked
+// by the config_yaml validators.
+func (m RulerNamespaceResourceModel) checkRulePolicies(ruleNamespace rules.RuleNamespace, providerPolicy *rulePolicyModel, diagnostics *diag.Diagnostics) {
+	if m.RulePolicy != nil {
+		policy := m.RulePolicy.labelChecks()
+		addRulePolicyDiagnostics(diagnostics, path.Root("config_yaml"), policy, policy.check(ruleNamespace))
+	}
+	if providerPolicy != nil {
+		policy := providerPolicy.withoutOverrides(m.RulePolicy)
+		addRulePolicyDiagnostics(diagnostics, path.Root("config_yaml"), policy, policy.check(ruleNamespace))
+	}
 }
 
 func (r *RulerNamespaceResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
@@ -138,11 +448,20 @@ func (r *RulerNamespaceResource) Create(ctx context.Context, req resource.Create
 	}
 
 	// Extract values from the plan
+	client := r.rulerClients[plan.rulerBackend()]
 	namespace := plan.Namespace.ValueString()
 	ruleGroup := plan.ConfigYAML.ValueString()
 	strictRecordingRuleCheck := plan.StrictRecordingRuleCheck.ValueBool()
 	recordingRuleCheck := plan.RecordingRuleCheck.ValueBool()
 
+	createTimeout, diags := plan.Timeouts.Create(ctx, defaultTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, createTimeout)
+	defer cancel()
+
 	tflog.Debug(ctx, "CREATE - values from plan", map[string]interface{}{
 		"namespace":                namespace,
 		"ruleGroup":                ruleGroup,
@@ -151,7 +470,7 @@ func (r *RulerNamespaceResource) Create(ctx context.Context, req resource.Create
 	})
 
 	// Parse YAML
-	ruleNamespace, err := getRuleNamespaceFromYAML(ctx, ruleGroup)
+	ruleNamespace, err := getRuleNamespaceFromYAML(ctx, ruleGroup, plan.rulerBackend())
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Failed to parse rule group YAML",
@@ -160,24 +479,31 @@ func (r *RulerNamespaceResource) Create(ctx context.Context, req resource.Create
 		return
 	}
 
-	if recordingRuleCheck {
-		err = checkRecordingRules(ruleNamespace, strictRecordingRuleCheck)
-		if err != nil {
-			// TODO: add a more explicit error message
-			resp.Diagnostics.AddError(
-				"Failed to check recording rule group",
-				err.Error(),
-			)
-			return
-		}
+	if recordingRuleCheck && !checkRecordingRules(ruleNamespace, strictRecordingRuleCheck, &resp.Diagnostics) {
+		return
+	}
+
+	preparedNamespace, preparedConfigYAML, err := plan.preparedConfigYAML(ruleNamespace, r.defaultRuleLabels)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("prepare_aggregation_label"), "Failed to prepare rules", err.Error())
+		return
+	}
+	plan.PreparedConfigYAML = preparedConfigYAML
+	plan.GroupSourceTenants, diags = groupSourceTenants(ctx, ruleNamespace.Groups)
+	resp.Diagnostics.Append(diags...)
+
+	// Refuse to clobber rule groups managed elsewhere
+	owned := plan.ownedRuleGroups(ctx)
+	if !plan.AllowOverwrite.ValueBool() && !checkNamespaceIsFree(ctx, client, namespace, owned, &resp.Diagnostics) {
+		return
+	}
+	if plan.rulerBackend() == rulerBackendMimir &&
+		!checkRulerNamespaceLimits(ctx, r.rulerLimits, client, namespace, preparedNamespace.Groups, owned, &resp.Diagnostics) {
+		return
 	}
 
 	// Create rule groups in Mimir
-	if err := createAllRuleGroups(ctx, r.client, namespace, ruleNamespace.Groups); err != nil {
-		resp.Diagnostics.AddError(
-			"Failed to create rule groups",
-			err.Error(),
-		)
+	if !applyRuleGroups(ctx, client, namespace, preparedNamespace.Groups, owned, &resp.Diagnostics) {
 		return
 	}
 
@@ -185,7 +511,7 @@ func (r *RulerNamespaceResource) Create(ctx context.Context, req resource.Create
 	plan.ID = types.StringValue(hash(namespace))
 
 	// Always fetch canonical YAML from backend and store in state
-	normalized, ok := fetchAndNormalizeRemoteConfigYAML(ctx, r.client, namespace, "CREATE", &resp.Diagnostics)
+	normalized, ok := fetchAndNormalizeRemoteConfigYAML(ctx, client, namespace, owned, plan.rulerBackend(), "CREATE", &resp.Diagnostics)
 	if !ok {
 		return
 	}
@@ -203,14 +529,40 @@ func (r *RulerNamespaceResource) Read(ctx context.Context, req resource.ReadRequ
 		return
 	}
 
+	readTimeout, diags := state.Timeouts.Read(ctx, defaultTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	client := r.rulerClients[state.rulerBackend()]
 	namespace := state.Namespace.ValueString()
 
-	// Use the same helper as Create/Update for fetching and normalizing YAML
-	normalized, ok := fetchAndNormalizeRemoteConfigYAML(ctx, r.client, namespace, "READ", &resp.Diagnostics)
+	groups, _, err := listRemoteRuleGroups(ctx, client, namespace)
+	if err != nil {
+		addAPIErrorDiagnostic(&resp.Diagnostics, fmt.Sprintf("read the rule groups of namespace %q", namespace), err)
+		return
+	}
+	// Groups owned by other tools are not part of this resource
+	groups = filterOwnedRuleGroups(groups, state.ownedRuleGroups(ctx))
+	if len(groups) == 0 {
+		tflog.Info(ctx, "No rule groups found in backend for namespace; removing from state", map[string]interface{}{"namespace": namespace})
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	// Use the same helper as Create/Update for normalizing YAML
+	normalized, ok := normalizeRemoteRuleGroups(ctx, groups, state.rulerBackend(), "READ", &resp.Diagnostics)
 	if !ok {
 		return
 	}
 	state.RemoteConfigYAML = types.StringValue(normalized)
+	state.GroupSourceTenants, diags = groupSourceTenants(ctx, groups)
+	resp.Diagnostics.Append(diags...)
+	// States written before the backend attribute existed are mimir ones
+	state.Backend = types.StringValue(state.rulerBackend())
 	state.ID = types.StringValue(hash(namespace))
 	tflog.Debug(ctx, "Read: setting state.ID", map[string]interface{}{"id": state.ID.ValueString()})
 	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
@@ -222,24 +574,29 @@ func (r *RulerNamespaceResource) Delete(ctx context.Context, req resource.Delete
 
 	// Read Terraform prior state data into the model
 	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	deleteTimeout, diags := state.Timeouts.Delete(ctx, defaultTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, deleteTimeout)
+	defer cancel()
 
 	// Extract namespace from state
+	client := r.rulerClients[state.rulerBackend()]
 	namespace := state.Namespace.ValueString()
 
 	tflog.Debug(ctx, "DELETE - values from state", map[string]interface{}{
 		"state_config_yaml": state.ConfigYAML.ValueString(),
 	})
 
-	err := r.client.DeleteNamespace(ctx, namespace)
-
-	if err != nil && !strings.Contains(err.Error(), "not found") {
-		resp.Diagnostics.AddError(
-			"Unable to Delete Resource",
-			"An unexpected error occurred while attempting to delete the resource. "+
-				"Please retry the operation or report this issue to the provider developers.\n\n"+
-				"HTTP Error: "+err.Error(),
-		)
-
+	err := deleteOwnedRuleGroups(ctx, client, namespace, state.ownedRuleGroups(ctx))
+	if err != nil {
+		addAPIErrorDiagnostic(&resp.Diagnostics, fmt.Sprintf("delete namespace %q", namespace), err)
 		return
 	}
 
@@ -249,30 +606,58 @@ func (r *RulerNamespaceResource) Delete(ctx context.Context, req resource.Delete
 
 func (r *RulerNamespaceResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
 	tflog.Debug(ctx, "IMPORT STATE - init")
-	// The import ID is the namespace name
-	namespace := req.ID
+	// The import ID is the namespace name, prefixed by the backend for the other ones than mimir
+	backend, namespace := parseImportID(req.ID)
+	client := r.rulerClients[backend]
 
 	// Create a state with the namespace set
 	var state RulerNamespaceResourceModel
 	state.Namespace = types.StringValue(namespace)
+	state.Backend = types.StringValue(backend)
 	state.ID = types.StringValue(hash(namespace))
+	state.Timeouts = nullTimeouts()
+	// Collections need their element type even when null
+	state.ConfigFiles = types.ListNull(types.StringType)
+	state.ConfigFileChecksums = types.MapNull(types.StringType)
+	state.PrepareIgnoredRules = types.SetNull(types.StringType)
+	state.ExtraLabels = types.MapNull(types.StringType)
+	state.ExtraAnnotations = types.MapNull(types.StringType)
 
 	// Fetch backend rules to update the state
-	normalized, ok := fetchAndNormalizeRemoteConfigYAML(ctx, r.client, namespace, "IMPORT", &resp.Diagnostics)
+	groups, found, err := listRemoteRuleGroups(ctx, client, namespace)
+	if err != nil {
+		addAPIErrorDiagnostic(&resp.Diagnostics, fmt.Sprintf("read the rule groups of namespace %q", namespace), err)
+		return
+	}
+	if !found {
+		resp.Diagnostics.AddError(
+			"Cannot Import Non-Existent Namespace",
+			fmt.Sprintf("No rule groups were found in Grafana Mimir for namespace %q.", namespace),
+		)
+		return
+	}
+	normalized, ok := normalizeRemoteRuleGroups(ctx, groups, backend, "IMPORT", &resp.Diagnostics)
 	if !ok {
 		return
 	}
 	state.RemoteConfigYAML = types.StringValue(normalized)
+	var diags diag.Diagnostics
+	state.GroupSourceTenants, diags = groupSourceTenants(ctx, groups)
+	resp.Diagnostics.Append(diags...)
 	// state.ConfigYAML = types.StringValue(normalized) // Set config_yaml to the same value for import
 
 	// Set the state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
 }
 
-func getRuleNamespaceFromYAML(_ context.Context, configYAML string) (rules.RuleNamespace, error) {
+func getRuleNamespaceFromYAML(_ context.Context, configYAML string, backend string) (rules.RuleNamespace, error) {
 	var ruleNamespace rules.RuleNamespace
+	parse := rules.ParseBytes
+	if !usesPromQL(backend) {
+		parse = parseLogQLRuleNamespaces
+	}
 	// We pass only one ruleGroup while ParseBytes return an array, we only need the first element
-	ruleNamespaces, err := rules.ParseBytes([]byte(configYAML))
+	ruleNamespaces, err := parse([]byte(configYAML))
 	if err != nil {
 		return ruleNamespace, fmt.Errorf("failed to parse namespace definition:\n%s", err)
 	}
@@ -286,16 +671,61 @@ func getRuleNamespaceFromYAML(_ context.Context, configYAML string) (rules.RuleN
 	return ruleNamespace, fmt.Errorf("no namespace definition found")
 }
 
-func checkRecordingRules(ruleNamespace rules.RuleNamespace, strict bool) error {
-	invalidRulesCount := ruleNamespace.CheckRecordingRules(strict)
-	if invalidRulesCount > 0 {
-		return fmt.Errorf("namespace contains %d rules that don't match the requirements", invalidRulesCount)
+// recordingRuleIssue is a recording rule whose name doesn't follow the
+// level:metric:operations naming convention.
+type recordingRuleIssue struct {
+	Group  string
+	Record string
+	Expr   string
+	Reason string
+}
+
+// findRecordingRuleIssues mirrors rules.RuleNamespace.CheckRecordingRules but
+// returns every offending rule instead of their count.
+func findRecordingRuleIssues(ruleNamespace rules.RuleNamespace, strict bool) []recordingRuleIssue {
+	reqChunks := 2
+	reason := "must contain at least one colon to match the level:metric:operations format"
+	if strict {
+		reqChunks = 3
+		reason = "must contain at least two colons to match the level:metric:operations format (strict_recording_rule_check is enabled)"
+	}
+
+	var issues []recordingRuleIssue
+	for _, group := range ruleNamespace.Groups {
+		for _, rule := range group.Rules {
+			// Assume if there is a rule.Record that this is a recording rule.
+			if rule.Record.Value == "" {
+				continue
+			}
+			if len(strings.Split(rule.Record.Value, ":")) < reqChunks {
+				issues = append(issues, recordingRuleIssue{
+					Group:  group.Name,
+					Record: rule.Record.Value,
+					Expr:   rule.Expr.Value,
+					Reason: reason,
+				})
+			}
+		}
+	}
+	return issues
+}
+
+// checkRecordingRules reports each recording rule breaking the naming
+// convention as a config_yaml diagnostic, false is returned if any does.
+func checkRecordingRules(ruleNamespace rules.RuleNamespace, strict bool, diagnostics *diag.Diagnostics) bool {
+	issues := findRecordingRuleIssues(ruleNamespace, strict)
+	for _, issue := range issues {
+		diagnostics.AddAttributeError(
+			path.Root("config_yaml"),
+			"Invalid Recording Rule Name",
+			fmt.Sprintf("Recording rule %q in group %q %s.\n\nExpression: %s", issue.Record, issue.Group, issue.Reason, issue.Expr),
+		)
 	}
-	return nil
+	return len(issues) == 0
 }
 
 // Borrowed from https://github.com/grafana/terraform-provider-grafana/blob/main/internal/resources/grafana/resource_dashboard.go
-func normalizeNamespaceYAML(config any) (string, int, int, error) {
+func normalizeNamespaceYAML(config any, backend string) (string, int, int, error) {
 	configYAML := config.(string)
 	var ruleNamespace rules.RuleNamespace
 
@@ -303,7 +733,10 @@ func normalizeNamespaceYAML(config any) (string, int, int, error) {
 	if err != nil {
 		return "", 0, 0, fmt.Errorf("failed to unmarshal YAML config")
 	}
-	count, mod, _ := ruleNamespace.LintExpressions(rules.MimirBackend)
+	var count, mod int
+	if usesPromQL(backend) {
+		count, mod, _ = ruleNamespace.LintExpressions(rules.MimirBackend)
+	}
 
 	namespaceBytes, _ := yaml.Marshal(ruleNamespace)
 	return string(namespaceBytes), count, mod, err
@@ -321,47 +754,66 @@ func (r *RulerNamespaceResource) Update(ctx context.Context, req resource.Update
 		return
 	}
 
+	updateTimeout, diags := plan.Timeouts.Update(ctx, defaultTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, updateTimeout)
+	defer cancel()
+
+	client := r.rulerClients[plan.rulerBackend()]
 	namespace := plan.Namespace.ValueString()
 	ruleGroup := plan.ConfigYAML.ValueString()
 	strictRecordingRuleCheck := plan.StrictRecordingRuleCheck.ValueBool()
 	recordingRuleCheck := plan.RecordingRuleCheck.ValueBool()
 
-	// Delete the current namespace to replace it
-	err := r.client.DeleteNamespace(ctx, namespace)
+	ruleNamespace, err := getRuleNamespaceFromYAML(ctx, ruleGroup, plan.rulerBackend())
 	if err != nil {
 		resp.Diagnostics.AddError(
-			"Failed to delete existing namespace",
+			"Failed to parse rule group YAML",
 			err.Error(),
 		)
 		return
 	}
 
-	ruleNamespace, err := getRuleNamespaceFromYAML(ctx, ruleGroup)
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"Failed to parse rule group YAML",
-			err.Error(),
-		)
+	if recordingRuleCheck && !checkRecordingRules(ruleNamespace, strictRecordingRuleCheck, &resp.Diagnostics) {
 		return
 	}
 
-	if recordingRuleCheck {
-		err = checkRecordingRules(ruleNamespace, strictRecordingRuleCheck)
-		if err != nil {
-			resp.Diagnostics.AddError(
-				"Failed to check recording rule group",
-				err.Error(),
-			)
-			return
+	preparedNamespace, preparedConfigYAML, err := plan.preparedConfigYAML(ruleNamespace, r.defaultRuleLabels)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("prepare_aggregation_label"), "Failed to prepare rules", err.Error())
+		return
+	}
+	plan.PreparedConfigYAML = preparedConfigYAML
+	plan.GroupSourceTenants, diags = groupSourceTenants(ctx, ruleNamespace.Groups)
+	resp.Diagnostics.Append(diags...)
+
+	// With managed_groups_only, the groups dropped from config_yaml are still owned and must be deleted
+	owned := plan.ownedRuleGroups(ctx)
+	if owned != nil {
+		for name := range declaredRuleGroupNames(ctx, state.ConfigYAML, state.rulerBackend()) {
+			owned[name] = true
 		}
 	}
 
-	// Create all rule groups for the namespace
-	if err := createAllRuleGroups(ctx, r.client, namespace, ruleNamespace.Groups); err != nil {
-		resp.Diagnostics.AddError(
-			"Failed to create rule groups",
-			err.Error(),
-		)
+	if plan.rulerBackend() == rulerBackendMimir &&
+		!checkRulerNamespaceLimits(ctx, r.rulerLimits, client, namespace, preparedNamespace.Groups, owned, &resp.Diagnostics) {
+		return
+	}
+
+	previousNamespace := state.Namespace.ValueString()
+	if previousNamespace != namespace {
+		// Renamed: the previous namespace is only deleted once the new one is live
+		if !plan.AllowOverwrite.ValueBool() && !checkNamespaceIsFree(ctx, client, namespace, plan.ownedRuleGroups(ctx), &resp.Diagnostics) {
+			return
+		}
+		if !renameNamespace(ctx, client, previousNamespace, namespace, preparedNamespace.Groups, owned, &resp.Diagnostics) {
+			return
+		}
+	} else if !applyRuleGroups(ctx, client, namespace, preparedNamespace.Groups, owned, &resp.Diagnostics) {
+		// Replace the rule groups of the namespace, groups that are no longer declared are removed
 		return
 	}
 
@@ -369,7 +821,7 @@ func (r *RulerNamespaceResource) Update(ctx context.Context, req resource.Update
 	plan.ID = types.StringValue(hash(namespace))
 
 	// Fetch backend rules
-	normalized, ok := fetchAndNormalizeRemoteConfigYAML(ctx, r.client, namespace, "UPDATE", &resp.Diagnostics)
+	normalized, ok := fetchAndNormalizeRemoteConfigYAML(ctx, client, namespace, plan.ownedRuleGroups(ctx), plan.rulerBackend(), "UPDATE", &resp.Diagnostics)
 	if !ok {
 		return
 	}
@@ -378,42 +830,97 @@ func (r *RulerNamespaceResource) Update(ctx context.Context, req resource.Update
 	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
 }
 
-// Create rule groups in Mimir
-func createAllRuleGroups(ctx context.Context, client *client.MimirClient, namespace string, groups []rwrulefmt.RuleGroup) error {
+// checkNamespaceIsFree reports an error if the namespace already contains rule
+// groups in Grafana Mimir. When owned is not nil, only these groups are checked.
+func checkNamespaceIsFree(ctx context.Context, client mimirClientInterface, namespace string, owned map[string]bool, diagnostics *diag.Diagnostics) bool {
+	groups, _, err := listRemoteRuleGroups(ctx, client, namespace)
+	if err != nil {
+		addAPIErrorDiagnostic(diagnostics, fmt.Sprintf("check whether namespace %q already exists", namespace), err)
+		return false
+	}
+	groups = filterOwnedRuleGroups(groups, owned)
+	if len(groups) == 0 {
+		return true
+	}
+
+	names := make([]string, 0, len(groups))
 	for _, group := range groups {
-		if err := client.CreateRuleGroup(ctx, namespace, group); err != nil {
-			return err
-		}
+		names = append(names, group.Name)
 	}
-	return nil
+	diagnostics.AddAttributeError(
+		path.Root("namespace"),
+		"Namespace Already Exists",
+		fmt.Sprintf("Namespace %q already contains rule groups in Grafana Mimir (%s), probably managed by another Terraform workspace or by hand. "+
+			"Import it with `terraform import <resource address> %s` to manage it with this resource, "+
+			"or set `allow_overwrite = true` to replace its content.", namespace, strings.Join(names, ", "), namespace),
+	)
+	return false
 }
 
 // Helper function for fetching and normalizing the remote config YAML
 func fetchAndNormalizeRemoteConfigYAML(
 	ctx context.Context,
-	client *client.MimirClient,
+	client mimirClientInterface,
 	namespace string,
+	owned map[string]bool,
+	backend string,
 	op string,
 	diagnostics *diag.Diagnostics,
 ) (string, bool) {
-	remoteNamespaceRuleGroup, err := client.ListRules(ctx, namespace)
+	groups, _, err := listRemoteRuleGroups(ctx, client, namespace)
 	if err != nil {
-		diagnostics.AddError(
-			fmt.Sprintf("Error Reading Mimir RuleGroup after %s", op),
-			fmt.Sprintf("Could not read Mimir rulegroup for namespace %q: %s", namespace, err.Error()),
-		)
+		addAPIErrorDiagnostic(diagnostics, fmt.Sprintf("read the rule groups of namespace %q after %s", namespace, op), err)
 		return "", false
 	}
+	return normalizeRemoteRuleGroups(ctx, filterOwnedRuleGroups(groups, owned), backend, op, diagnostics)
+}
+
+// ownedRuleGroups returns the names of the rule groups managed by the resource,
+// nil when it manages the whole namespace.
+func (m RulerNamespaceResourceModel) ownedRuleGroups(ctx context.Context) map[string]bool {
+	if !m.ManagedGroupsOnly.ValueBool() {
+		return nil
+	}
+	return declaredRuleGroupNames(ctx, m.ConfigYAML, m.rulerBackend())
+}
+
+// declaredRuleGroupNames returns the names of the rule groups declared in the
+// config, never nil so that an unreadable config doesn't claim the whole namespace.
+func declaredRuleGroupNames(ctx context.Context, configYAML types.String, backend string) map[string]bool {
+	if configYAML.IsNull() || configYAML.IsUnknown() {
+		return map[string]bool{}
+	}
+	ruleNamespace, err := getRuleNamespaceFromYAML(ctx, configYAML.ValueString(), backend)
+	if err != nil {
+		return map[string]bool{}
+	}
+	return ruleGroupNames(ruleNamespace.Groups)
+}
 
-	tflog.Trace(ctx, op+": raw value for remoteNamespaceRuleGroup", map[string]interface{}{"remoteNamespaceRuleGroup": remoteNamespaceRuleGroup})
+// listRemoteRuleGroups returns the rule groups stored in Grafana Mimir for the
+// namespace. found is false when the namespace doesn't exist, depending on the
+// version Mimir either answers 404 or an empty list in that case.
+func listRemoteRuleGroups(ctx context.Context, client mimirClientInterface, namespace string) ([]rwrulefmt.RuleGroup, bool, error) {
+	remoteNamespaceRuleGroup, err := client.ListRules(ctx, namespace)
+	if isNotFound(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	tflog.Trace(ctx, "raw value for remoteNamespaceRuleGroup", map[string]interface{}{"remoteNamespaceRuleGroup": remoteNamespaceRuleGroup})
 
+	groups := remoteNamespaceRuleGroup[namespace]
+	return groups, len(groups) > 0, nil
+}
+
+// normalizeRemoteRuleGroups renders the rule groups returned by Grafana Mimir as normalized namespace YAML
+func normalizeRemoteRuleGroups(ctx context.Context, groups []rwrulefmt.RuleGroup, backend string, op string, diagnostics *diag.Diagnostics) (string, bool) {
 	// Mimir top level key is the namespace name while in the YAML definition the top level key is groups
 	// Let's rename the key to be able to have a nice difference
 	// TODO: might not be needed anymore since we have introduced the remote_config_yaml attribute
-	remoteNamespaceRuleGroup["groups"] = remoteNamespaceRuleGroup[namespace]
-	delete(remoteNamespaceRuleGroup, namespace)
-
-	tflog.Debug(ctx, op+": after removing the namespace key", map[string]interface{}{"remoteNamespaceRuleGroupAfterRemovingNamespaceKey": remoteNamespaceRuleGroup})
+	remoteNamespaceRuleGroup := map[string][]rwrulefmt.RuleGroup{"groups": groups}
 
 	remoteConfigYAML, err := yaml.Marshal(remoteNamespaceRuleGroup)
 	if err != nil {
@@ -424,7 +931,7 @@ func fetchAndNormalizeRemoteConfigYAML(
 		return "", false
 	}
 	tflog.Debug(ctx, op+": YAML to be set in state", map[string]interface{}{"remote_config_yaml": remoteConfigYAML})
-	normalized, count, mod, err := normalizeNamespaceYAML(string(remoteConfigYAML))
+	normalized, count, mod, err := normalizeNamespaceYAML(string(remoteConfigYAML), backend)
 	if err != nil {
 		diagnostics.AddError(
 			fmt.Sprintf("Error while normalizing namespace YAML after %s", op),
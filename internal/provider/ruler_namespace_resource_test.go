@@ -4,14 +4,24 @@
 package provider
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"regexp"
+	"strings"
 	"testing"
 
+	"github.com/grafana/mimir/pkg/mimirtool/rules/rwrulefmt"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
 	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/plancheck"
 	"github.com/hashicorp/terraform-plugin-testing/statecheck"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
 	"github.com/hashicorp/terraform-plugin-testing/tfjsonpath"
 	"gopkg.in/yaml.v3"
 )
@@ -88,7 +98,7 @@ func TestAccResourceNamespace(t *testing.T) {
 				ImportState:       true,
 				ImportStateVerify: true,
 				// These fields can't be retrieved from mimir ruler
-				ImportStateVerifyIgnore: []string{"recording_rule_check", "strict_recording_rule_check", "config_yaml"},
+				ImportStateVerifyIgnore: []string{"recording_rule_check", "strict_recording_rule_check", "allow_overwrite", "managed_groups_only", "lint_mode", "config_yaml"},
 			},
 		},
 	})
@@ -110,6 +120,11 @@ func TestAccResourceNamespaceRename(t *testing.T) {
 			},
 			{
 				Config: testAccResourceNamespaceRenameAfterUpdate,
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction("mimirtool_ruler_namespace.alerts", plancheck.ResourceActionUpdate),
+					},
+				},
 				ConfigStateChecks: []statecheck.StateCheck{
 					statecheck.ExpectKnownValue(
 						"mimirtool_ruler_namespace.alerts",
@@ -117,6 +132,16 @@ func TestAccResourceNamespaceRename(t *testing.T) {
 						knownvalue.StringExact("infra"),
 					),
 				},
+				Check: func(_ *terraform.State) error {
+					_, found, err := listRemoteRuleGroups(context.Background(), testAccMimirClient(t), "alerts_infra")
+					if err != nil {
+						return err
+					}
+					if found {
+						return fmt.Errorf("previous namespace %q still exists after rename", "alerts_infra")
+					}
+					return nil
+				},
 			},
 		},
 	})
@@ -166,7 +191,19 @@ func TestAccResourceNamespaceCheckRules(t *testing.T) {
 		Steps: []resource.TestStep{
 			{
 				Config:      testAccResourceNamespaceFailsCheck,
-				ExpectError: regexp.MustCompile("namespace contains 1 rules that don't match the requirements"),
+				ExpectError: regexp.MustCompile(`Recording rule\s+"cluster_job_cortex_request_duration_seconds_99quantile"\s+in group\s+"mimir_api_1"`),
+			},
+		},
+	})
+}
+
+func TestAccResourceNamespaceLintFail(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccResourceNamespaceLintFail,
+				ExpectError: regexp.MustCompile(`PromQL expression not canonically formatted`),
 			},
 		},
 	})
@@ -208,6 +245,235 @@ func TestAccResourceNamespaceParseRules(t *testing.T) {
 	})
 }
 
+func TestAccResourceNamespaceDeletedOutsideTerraform(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceNamespace,
+			},
+			{
+				PreConfig: func() {
+					if err := testAccMimirClient(t).DeleteNamespace(context.Background(), "demo"); err != nil {
+						t.Fatalf("failed to delete namespace: %s", err)
+					}
+				},
+				RefreshState:       true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: testAccResourceNamespace,
+				ConfigStateChecks: []statecheck.StateCheck{
+					SemanticYAMLStateCheck("mimirtool_ruler_namespace.demo", "remote_config_yaml", testAccResourceNamespaceYaml),
+				},
+			},
+		},
+	})
+}
+
+func TestAccResourceNamespaceExisting(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		PreCheck: func() {
+			cli := testAccMimirClient(t)
+			ruleNamespace, err := getRuleNamespaceFromYAML(context.Background(), testAccResourceNamespaceYaml, rulerBackendMimir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, group := range ruleNamespace.Groups {
+				if err := cli.CreateRuleGroup(context.Background(), "existing", group); err != nil {
+					t.Fatalf("failed to create rule group: %s", err)
+				}
+			}
+		},
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccResourceNamespaceExisting,
+				ExpectError: regexp.MustCompile("Namespace Already Exists"),
+			},
+			{
+				Config: testAccResourceNamespaceExistingOverwrite,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("mimirtool_ruler_namespace.demo", "namespace", "existing"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceNamespaceManagedGroupsOnly(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		PreCheck: func() {
+			ruleNamespace, err := getRuleNamespaceFromYAML(context.Background(), testAccResourceNamespaceNoCheckExpected, rulerBackendMimir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			group := ruleNamespace.Groups[0]
+			group.Name = "pushed_by_ci"
+			if err := testAccMimirClient(t).CreateRuleGroup(context.Background(), "shared", group); err != nil {
+				t.Fatalf("failed to create rule group: %s", err)
+			}
+		},
+		CheckDestroy: func(_ *terraform.State) error {
+			groups, _, err := listRemoteRuleGroups(context.Background(), testAccMimirClient(t), "shared")
+			if err != nil {
+				return err
+			}
+			if len(groups) != 1 || groups[0].Name != "pushed_by_ci" {
+				return fmt.Errorf("expected only the group pushed by CI to remain, got %v", groups)
+			}
+			return testAccMimirClient(t).DeleteNamespace(context.Background(), "shared")
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceNamespaceManagedGroupsOnly,
+				ConfigStateChecks: []statecheck.StateCheck{
+					SemanticYAMLStateCheck("mimirtool_ruler_namespace.shared", "remote_config_yaml", testAccResourceNamespaceYaml),
+				},
+			},
+		},
+	})
+}
+
+func TestAccResourceNamespaceTimeouts(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceNamespaceTimeouts,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("mimirtool_ruler_namespace.demo", "timeouts.create", "1m"),
+					resource.TestCheckResourceAttr("mimirtool_ruler_namespace.demo", "timeouts.delete", "30s"),
+				),
+			},
+			{
+				Config:      testAccResourceNamespaceTimeoutsInvalid,
+				ExpectError: regexp.MustCompile("must be a string containing a sequence of decimal numbers"),
+			},
+		},
+	})
+}
+
+func TestAccResourceNamespaceConfigFiles(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceNamespaceConfigFiles,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("mimirtool_ruler_namespace.demo", "config_file_checksums.%", "2"),
+					resource.TestCheckResourceAttrSet("mimirtool_ruler_namespace.demo", "config_file_checksums.testdata/rule_files/api.yaml"),
+				),
+				ConfigStateChecks: []statecheck.StateCheck{
+					SemanticYAMLStateCheck("mimirtool_ruler_namespace.demo", "remote_config_yaml", testAccResourceNamespaceConfigFilesYaml),
+				},
+			},
+			{
+				Config:      testAccResourceNamespaceConfigFilesDuplicate,
+				ExpectError: regexp.MustCompile(`group "mimir_api_1" is already defined in testdata/rule_files/api.yaml`),
+			},
+		},
+	})
+}
+
+const testAccResourceNamespaceConfigFiles = `
+provider "mimirtool" {
+  address = "http://localhost:8080"
+}
+
+resource "mimirtool_ruler_namespace" "demo" {
+	namespace = "config_files"
+	config_files = ["testdata/rule_files"]
+  }
+`
+
+const testAccResourceNamespaceConfigFilesDuplicate = `
+provider "mimirtool" {
+  address = "http://localhost:8080"
+}
+
+resource "mimirtool_ruler_namespace" "demo" {
+	namespace = "config_files"
+	config_files = ["testdata/rule_files", "testdata/rules.yaml"]
+  }
+`
+
+const testAccResourceNamespaceConfigFilesYaml = `groups:
+- name: mimir_api_1
+  rules:
+  - record: cluster_job:cortex_request_duration_seconds:99quantile
+    expr: histogram_quantile(0.99, sum by (le, cluster, job) (rate(cortex_request_duration_seconds_bucket[1m])))
+- name: mimir_ingester
+  rules:
+  - record: cluster_job:cortex_ingester_memory_series:sum
+    expr: sum by (cluster, job) (cortex_ingester_memory_series)
+`
+
+const testAccResourceNamespaceExisting = `
+provider "mimirtool" {
+  address = "http://localhost:8080"
+}
+
+resource "mimirtool_ruler_namespace" "demo" {
+	namespace = "existing"
+	config_yaml = file("testdata/rules2.yaml")
+  }
+`
+
+const testAccResourceNamespaceExistingOverwrite = `
+provider "mimirtool" {
+  address = "http://localhost:8080"
+}
+
+resource "mimirtool_ruler_namespace" "demo" {
+	namespace = "existing"
+	config_yaml = file("testdata/rules2.yaml")
+	allow_overwrite = true
+  }
+`
+
+const testAccResourceNamespaceManagedGroupsOnly = `
+provider "mimirtool" {
+  address = "http://localhost:8080"
+}
+
+resource "mimirtool_ruler_namespace" "shared" {
+	namespace = "shared"
+	config_yaml = file("testdata/rules.yaml")
+	managed_groups_only = true
+  }
+`
+
+const testAccResourceNamespaceTimeouts = `
+provider "mimirtool" {
+  address = "http://localhost:8080"
+}
+
+resource "mimirtool_ruler_namespace" "demo" {
+	namespace = "demo"
+	config_yaml = file("testdata/rules.yaml")
+	timeouts {
+	  create = "1m"
+	  delete = "30s"
+	}
+  }
+`
+
+const testAccResourceNamespaceTimeoutsInvalid = `
+provider "mimirtool" {
+  address = "http://localhost:8080"
+}
+
+resource "mimirtool_ruler_namespace" "demo" {
+	namespace = "demo"
+	config_yaml = file("testdata/rules.yaml")
+	timeouts {
+	  create = "one minute"
+	}
+  }
+`
+
 const testAccResourceNamespaceRename = `
 provider "mimirtool" {
   address = "http://localhost:8080"
@@ -302,6 +568,24 @@ resource "mimirtool_ruler_namespace" "demo" {
   }
 `
 
+const testAccResourceNamespaceLintFail = `
+provider "mimirtool" {
+  address = "http://localhost:8080"
+}
+
+resource "mimirtool_ruler_namespace" "demo" {
+	namespace = "demo"
+	lint_mode = "fail"
+	config_yaml = <<EOT
+groups:
+- name: lint
+  rules:
+  - record: job:up:max
+    expr: max by(job)(up)
+EOT
+  }
+`
+
 const testAccResourceNamespaceNoCheck = `
 provider "mimirtool" {
   address = "http://localhost:8080"
@@ -365,3 +649,285 @@ const testAccResourceNamespaceQuotingExpected = `groups:
                   LABELS = {{ $labels }}
             summary: Host high CPU load (instance {{ $labels.instance }})
 `
+
+func TestFindRecordingRuleIssues(t *testing.T) {
+	ruleNamespace, err := getRuleNamespaceFromYAML(context.Background(), `groups:
+- name: g1
+  rules:
+  - record: job:up:sum
+    expr: sum by (job) (up)
+  - record: job:up
+    expr: max by (job) (up)
+  - alert: Down
+    expr: up == 0
+- name: g2
+  rules:
+  - record: up_total
+    expr: sum(up)
+`, rulerBackendMimir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issues := findRecordingRuleIssues(ruleNamespace, false)
+	if len(issues) != 1 || issues[0].Group != "g2" || issues[0].Record != "up_total" || issues[0].Expr != "sum(up)" {
+		t.Fatalf("unexpected issues: %+v", issues)
+	}
+
+	issues = findRecordingRuleIssues(ruleNamespace, true)
+	if len(issues) != 2 || issues[0].Record != "job:up" || issues[1].Record != "up_total" {
+		t.Fatalf("unexpected strict issues: %+v", issues)
+	}
+}
+
+func TestLintRuleExpressions(t *testing.T) {
+	issues := lintRuleExpressions(`groups:
+- name: g1
+  rules:
+  - record: job:up:sum
+    expr: sum by (job) (up)
+  - record: job:up:max
+    expr: max by(job)(up)
+  - alert: Down
+    expr: up ==
+- name: g2
+  rules:
+  - alert: Broken
+    expr: |
+      sum(rate(foo[5m])) +* 2
+        / on() bar
+`)
+	if len(issues) != 3 {
+		t.Fatalf("expected 3 issues, got %+v", issues)
+	}
+
+	if issues[0].Rule != "job:up:max" || issues[0].Err != nil || issues[0].Formatted != "max by (job) (up)" {
+		t.Fatalf("unexpected formatting issue: %+v", issues[0])
+	}
+	if issues[1].Group != "g1" || issues[1].Rule != "Down" || issues[1].Err == nil || issues[1].Line != 9 || issues[1].Column != 16 {
+		t.Fatalf("unexpected parse issue: %+v", issues[1])
+	}
+	if issues[2].Group != "g2" || issues[2].Rule != "Broken" || issues[2].Err == nil || issues[2].Line != 14 || issues[2].Column != 27 {
+		t.Fatalf("unexpected parse issue in block scalar: %+v", issues[2])
+	}
+}
+
+func TestPrepareRuleNamespace(t *testing.T) {
+	ruleNamespace, err := getRuleNamespaceFromYAML(context.Background(), `groups:
+- name: g1
+  rules:
+  - record: job:up:sum
+    expr: sum by (job) (up)
+  - record: job:up:ratio
+    expr: sum by (job) (up) / on (job) group_left () sum by (job) (up_total)
+  - record: up:count
+    expr: count(up)
+`, rulerBackendMimir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := prepareRuleNamespace(ruleNamespace, "cluster", map[string]bool{"up:count": true}); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"sum by (job, cluster) (up)",
+		"sum by (job, cluster) (up) / on (job, cluster) group_left () sum by (job, cluster) (up_total)",
+		"count(up)",
+	}
+	for i, rule := range ruleNamespace.Groups[0].Rules {
+		if rule.Expr.Value != expected[i] {
+			t.Errorf("rule %d: expected %q, got %q", i, expected[i], rule.Expr.Value)
+		}
+	}
+}
+
+func TestInjectSelectorMatchers(t *testing.T) {
+	ruleNamespace, err := getRuleNamespaceFromYAML(context.Background(), `groups:
+- name: g1
+  rules:
+  - record: job:up:sum
+    expr: sum by (job) (rate(http_requests_total[5m])) / sum by (job) (up{cluster="prod-eu"})
+  - alert: Down
+    expr: up{cluster="prod-us"} == 0
+`, rulerBackendMimir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	matchers, err := parseSelectorMatchers(`{cluster="prod-eu"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errs := injectSelectorMatchers(ruleNamespace, matchers)
+	if len(errs) != 1 || !regexp.MustCompile(`rule "Down".*cluster="prod-us"`).MatchString(errs[0].Error()) {
+		t.Fatalf("expected a conflict on the alert, got %v", errs)
+	}
+	expected := `sum by (job) (rate(http_requests_total{cluster="prod-eu"}[5m])) / sum by (job) (up{cluster="prod-eu"})`
+	if expr := ruleNamespace.Groups[0].Rules[0].Expr.Value; expr != expected {
+		t.Fatalf("expected %q, got %q", expected, expr)
+	}
+	if expr := ruleNamespace.Groups[0].Rules[1].Expr.Value; expr != `up{cluster="prod-us"} == 0` {
+		t.Fatalf("conflicting rule must be left as is, got %q", expr)
+	}
+
+	if _, err := parseSelectorMatchers(`cluster="prod-eu"`); err == nil {
+		t.Fatal("expected an error for a selector without braces")
+	}
+}
+
+func TestMergeRuleLabels(t *testing.T) {
+	ruleNamespace, err := getRuleNamespaceFromYAML(context.Background(), `groups:
+- name: g1
+  rules:
+  - record: job:up:sum
+    expr: sum by (job) (up)
+  - alert: Down
+    expr: up == 0
+    labels:
+      team: sre
+    annotations:
+      summary: down
+`, rulerBackendMimir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	model := RulerNamespaceResourceModel{
+		ExtraLabels:      types.MapValueMust(types.StringType, map[string]attr.Value{"env": types.StringValue("prod"), "team": types.StringValue("platform")}),
+		ExtraAnnotations: types.MapValueMust(types.StringType, map[string]attr.Value{"runbook": types.StringValue("https://runbooks/down")}),
+	}
+	prepared, preparedYAML, err := model.preparedConfigYAML(ruleNamespace, map[string]string{"env": "dev", "region": "eu"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preparedYAML.IsNull() {
+		t.Fatal("expected the prepared rules to be set")
+	}
+	// The parsed rules are left as is
+	if ruleNamespace.Groups[0].Rules[0].Labels != nil {
+		t.Fatalf("expected the parsed rules to be unchanged, got labels %v", ruleNamespace.Groups[0].Rules[0].Labels)
+	}
+
+	recording, alert := prepared.Groups[0].Rules[0], prepared.Groups[0].Rules[1]
+	if !reflect.DeepEqual(recording.Labels, map[string]string{"env": "prod", "team": "platform", "region": "eu"}) || recording.Annotations != nil {
+		t.Fatalf("unexpected recording rule labels %v and annotations %v", recording.Labels, recording.Annotations)
+	}
+	if !reflect.DeepEqual(alert.Labels, map[string]string{"env": "prod", "team": "sre", "region": "eu"}) {
+		t.Fatalf("unexpected alerting rule labels %v", alert.Labels)
+	}
+	if !reflect.DeepEqual(alert.Annotations, map[string]string{"summary": "down", "runbook": "https://runbooks/down"}) {
+		t.Fatalf("unexpected alerting rule annotations %v", alert.Annotations)
+	}
+}
+
+func TestRunRuleTests(t *testing.T) {
+	ruleNamespace, err := getRuleNamespaceFromYAML(context.Background(), `groups:
+- name: g1
+  rules:
+  - record: job:up:sum
+    expr: sum by (job) (up)
+  - alert: InstanceDown
+    expr: up == 0
+    for: 5m
+    labels:
+      severity: page
+    annotations:
+      summary: '{{ $labels.instance }} is down'
+`, rulerBackendMimir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	passing := `evaluation_interval: 1m
+tests:
+- interval: 1m
+  input_series:
+  - series: up{job="api", instance="a"}
+    values: 1 1 1 0 0 0 0 0 0 0
+  - series: up{job="api", instance="b"}
+    values: 1x10
+  alert_rule_test:
+  - eval_time: 2m
+    alertname: InstanceDown
+  - eval_time: 9m
+    alertname: InstanceDown
+    exp_alerts:
+    - exp_labels:
+        severity: page
+        job: api
+        instance: a
+      exp_annotations:
+        summary: a is down
+  promql_expr_test:
+  - expr: job:up:sum
+    eval_time: 4m
+    exp_samples:
+    - labels: 'job:up:sum{job="api"}'
+      value: 1
+`
+	if errs := runRuleTests(ruleNamespace, passing); len(errs) > 0 {
+		t.Fatalf("expected the tests to pass, got %v", errs)
+	}
+
+	failing := `tests:
+- name: down
+  interval: 1m
+  input_series:
+  - series: up{job="api", instance="a"}
+    values: 0x10
+  alert_rule_test:
+  - eval_time: 2m
+    alertname: InstanceDown
+    exp_alerts:
+    - exp_labels:
+        severity: page
+        job: api
+        instance: a
+  promql_expr_test:
+  - expr: job:up:sum
+    eval_time: 1m
+    exp_samples:
+    - labels: 'job:up:sum{job="api"}'
+      value: 1
+`
+	errs := runRuleTests(ruleNamespace, failing)
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 failures, got %v", errs)
+	}
+	if !strings.Contains(errs[0].Error(), `test "down": alertname "InstanceDown", time 2m`) {
+		t.Fatalf("unexpected alert failure %q", errs[0])
+	}
+	if !strings.Contains(errs[1].Error(), `expr "job:up:sum", time 1m`) {
+		t.Fatalf("unexpected expression failure %q", errs[1])
+	}
+
+	if errs := runRuleTests(ruleNamespace, "tests:\n- unknown: 1\n"); len(errs) != 1 {
+		t.Fatalf("expected a parse error, got %v", errs)
+	}
+}
+
+func TestImportStateNamespace(t *testing.T) {
+	ctx := context.Background()
+	cli := newFakeMimirClient()
+	cli.namespaces["demo"] = map[string]rwrulefmt.RuleGroup{"a": testRuleGroup("a", 1)}
+	r := &RulerNamespaceResource{rulerClients: map[string]mimirClientInterface{rulerBackendMimir: cli}}
+
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+	resp := &fwresource.ImportStateResponse{
+		State: tfsdk.State{Schema: schemaResp.Schema, Raw: tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil)},
+	}
+	r.ImportState(ctx, fwresource.ImportStateRequest{ID: "demo"}, resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", resp.Diagnostics)
+	}
+
+	var state RulerNamespaceResourceModel
+	if diags := resp.State.Get(ctx, &state); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if state.Namespace.ValueString() != "demo" || state.RemoteConfigYAML.IsNull() {
+		t.Fatalf("unexpected imported state: %+v", state)
+	}
+}
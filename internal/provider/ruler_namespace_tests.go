@@ -0,0 +1,423 @@
+package provider
+
+// The rule unit tests are adapted from Prometheus' cmd/promtool/unittest.go
+// (Copyright 2018 The Prometheus Authors, Apache License 2.0) to run against
+// the rule groups of a namespace instead of rule files.
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	mimirrules "github.com/grafana/mimir/pkg/mimirtool/rules"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/rulefmt"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/promql/promqltest"
+	"github.com/prometheus/prometheus/rules"
+	"github.com/prometheus/prometheus/storage"
+	"gopkg.in/yaml.v3"
+)
+
+// ruleTestsFile is the promtool unit test format. rule_files is accepted for
+// compatibility but ignored, the tests run against the namespace's groups.
+type ruleTestsFile struct {
+	RuleFiles          []string        `yaml:"rule_files"`
+	EvaluationInterval model.Duration  `yaml:"evaluation_interval,omitempty"`
+	GroupEvalOrder     []string        `yaml:"group_eval_order"`
+	Tests              []ruleTestGroup `yaml:"tests"`
+}
+
+// ruleTestGroup is a group of input series and tests associated with it.
+type ruleTestGroup struct {
+	Interval        model.Duration       `yaml:"interval"`
+	InputSeries     []ruleTestSeries     `yaml:"input_series"`
+	AlertRuleTests  []alertRuleTestCase  `yaml:"alert_rule_test,omitempty"`
+	PromqlExprTests []promqlExprTestCase `yaml:"promql_expr_test,omitempty"`
+	ExternalLabels  labels.Labels        `yaml:"external_labels,omitempty"`
+	ExternalURL     string               `yaml:"external_url,omitempty"`
+	TestGroupName   string               `yaml:"name,omitempty"`
+}
+
+type ruleTestSeries struct {
+	Series string `yaml:"series"`
+	Values string `yaml:"values"`
+}
+
+type alertRuleTestCase struct {
+	EvalTime  model.Duration  `yaml:"eval_time"`
+	Alertname string          `yaml:"alertname"`
+	ExpAlerts []expectedAlert `yaml:"exp_alerts"`
+}
+
+type expectedAlert struct {
+	ExpLabels      map[string]string `yaml:"exp_labels"`
+	ExpAnnotations map[string]string `yaml:"exp_annotations"`
+}
+
+type promqlExprTestCase struct {
+	Expr       string           `yaml:"expr"`
+	EvalTime   model.Duration   `yaml:"eval_time"`
+	ExpSamples []expectedSample `yaml:"exp_samples"`
+}
+
+type expectedSample struct {
+	Labels    string  `yaml:"labels"`
+	Value     float64 `yaml:"value"`
+	Histogram string  `yaml:"histogram"` // A non-empty string means Value is ignored.
+}
+
+// parseRuleTests decodes promtool unit tests
+func parseRuleTests(testsYAML string) (ruleTestsFile, error) {
+	var file ruleTestsFile
+	decoder := yaml.NewDecoder(bytes.NewReader([]byte(testsYAML)))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return file, fmt.Errorf("failed to parse the rule tests: %w", err)
+	}
+	if file.EvaluationInterval == 0 {
+		file.EvaluationInterval = model.Duration(time.Minute)
+	}
+	for i, test := range file.Tests {
+		if test.Interval == 0 {
+			file.Tests[i].Interval = file.EvaluationInterval
+		}
+	}
+	return file, nil
+}
+
+// runRuleTests runs promtool unit tests against the rule groups of the
+// namespace with an in-memory storage and PromQL engine. The failures are
+// returned, an empty slice means that all the tests passed.
+func runRuleTests(ruleNamespace mimirrules.RuleNamespace, testsYAML string) []error {
+	file, err := parseRuleTests(testsYAML)
+	if err != nil {
+		return []error{err}
+	}
+
+	// Groups are evaluated in the namespace order, unless group_eval_order says otherwise
+	groupOrder := make(map[string]int, len(ruleNamespace.Groups))
+	for i, group := range ruleNamespace.Groups {
+		groupOrder[group.Name] = len(file.GroupEvalOrder) + i
+	}
+	for i, name := range file.GroupEvalOrder {
+		if _, ok := groupOrder[name]; !ok {
+			return []error{fmt.Errorf("group %q of group_eval_order is not in the namespace", name)}
+		}
+		groupOrder[name] = i
+	}
+
+	groups := &rulefmt.RuleGroups{}
+	for _, group := range ruleNamespace.Groups {
+		groups.Groups = append(groups.Groups, group.RuleGroup)
+	}
+
+	var errs []error
+	for _, test := range file.Tests {
+		errs = append(errs, test.run(time.Duration(file.EvaluationInterval), groups, groupOrder)...)
+	}
+	return errs
+}
+
+// namespaceGroupLoader loads the rule groups of a namespace from memory
+type namespaceGroupLoader struct {
+	groups *rulefmt.RuleGroups
+}
+
+func (l namespaceGroupLoader) Load(_ string) (*rulefmt.RuleGroups, []error) {
+	return l.groups, nil
+}
+
+func (l namespaceGroupLoader) Parse(query string) (parser.Expr, error) {
+	return parser.ParseExpr(query)
+}
+
+func (tg *ruleTestGroup) run(evalInterval time.Duration, ruleGroups *rulefmt.RuleGroups, groupOrder map[string]int) (outErr []error) {
+	var testName string
+	if tg.TestGroupName != "" {
+		testName = fmt.Sprintf("test %q: ", tg.TestGroupName)
+	}
+	fail := func(format string, args ...any) error {
+		return fmt.Errorf(testName+format, args...)
+	}
+
+	suite, err := promqltest.NewLazyLoader(tg.seriesLoadingString(), promqltest.LazyLoaderOpts{EnableAtModifier: true, EnableNegativeOffset: true})
+	if err != nil {
+		return []error{fail("%s", err)}
+	}
+	defer func() {
+		if err := suite.Close(); err != nil {
+			outErr = append(outErr, err)
+		}
+	}()
+	suite.SubqueryInterval = evalInterval
+
+	manager := rules.NewManager(&rules.ManagerOptions{
+		QueryFunc:   rules.EngineQueryFunc(suite.QueryEngine(), suite.Storage()),
+		Appendable:  suite.Storage(),
+		Context:     context.Background(),
+		NotifyFunc:  func(context.Context, string, ...*rules.Alert) {},
+		Logger:      log.NewNopLogger(),
+		GroupLoader: namespaceGroupLoader{groups: ruleGroups},
+	})
+	groupsMap, errs := manager.LoadGroups(time.Duration(tg.Interval), tg.ExternalLabels, tg.ExternalURL, nil, "namespace")
+	if errs != nil {
+		return errs
+	}
+	groups := make([]*rules.Group, 0, len(groupsMap))
+	for _, group := range groupsMap {
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groupOrder[groups[i].Name()] < groupOrder[groups[j].Name()]
+	})
+	for _, group := range groups {
+		for _, rule := range group.Rules() {
+			if alertRule, ok := rule.(*rules.AlertingRule); ok {
+				// Mark alerting rules as restored, to ensure the ALERTS timeseries is created when they run
+				alertRule.SetRestored(true)
+			}
+		}
+	}
+
+	mint := time.Unix(0, 0).UTC()
+	maxt := mint.Add(tg.maxEvalTime())
+
+	// The alerts are checked while the rules are evaluated
+	alertTests := make(map[model.Duration][]alertRuleTestCase)
+	var alertEvalTimes []model.Duration
+	for _, alert := range tg.AlertRuleTests {
+		if alert.Alertname == "" {
+			return []error{fail("an item under alert_rule_test misses required attribute alertname at eval_time %v", alert.EvalTime)}
+		}
+		if _, ok := alertTests[alert.EvalTime]; !ok {
+			alertEvalTimes = append(alertEvalTimes, alert.EvalTime)
+		}
+		alertTests[alert.EvalTime] = append(alertTests[alert.EvalTime], alert)
+	}
+	sort.Slice(alertEvalTimes, func(i, j int) bool { return alertEvalTimes[i] < alertEvalTimes[j] })
+
+	curr := 0
+	for ts := mint; !ts.After(maxt); ts = ts.Add(evalInterval) {
+		var evalErrs []error
+		suite.WithSamplesTill(ts, func(err error) {
+			if err != nil {
+				evalErrs = append(evalErrs, fail("%s", err))
+				return
+			}
+			for _, group := range groups {
+				group.Eval(suite.Context(), ts)
+				for _, rule := range group.Rules() {
+					if rule.LastError() != nil {
+						evalErrs = append(evalErrs, fail("rule %q, time %s: %s", rule.Name(), ts.Sub(mint), rule.LastError()))
+					}
+				}
+			}
+		})
+		if len(evalErrs) > 0 {
+			return append(errs, evalErrs...)
+		}
+
+		// Alerts with ts <= eval_time < ts+evalInterval are compared with this evaluation
+		for curr < len(alertEvalTimes) && ts.Sub(mint) <= time.Duration(alertEvalTimes[curr]) &&
+			time.Duration(alertEvalTimes[curr]) < ts.Add(evalInterval).Sub(mint) {
+			evalTime := alertEvalTimes[curr]
+			for _, testCase := range alertTests[evalTime] {
+				got := firingAlerts(groups, testCase.Alertname)
+
+				var expected alertLabelsAndAnnotations
+				for _, alert := range testCase.ExpAlerts {
+					// The alertname label is added by the evaluation
+					expLabels := map[string]string{labels.AlertName: testCase.Alertname}
+					for k, v := range alert.ExpLabels {
+						expLabels[k] = v
+					}
+					expected = append(expected, alertLabelsAndAnnotation{
+						Labels:      labels.FromMap(expLabels),
+						Annotations: labels.FromMap(alert.ExpAnnotations),
+					})
+				}
+				sort.Sort(got)
+				sort.Sort(expected)
+
+				if expected.String() != got.String() {
+					errs = append(errs, fail("alertname %q, time %s:\n  expected: %s\n  got: %s", testCase.Alertname, evalTime, expected, got))
+				}
+			}
+			curr++
+		}
+	}
+
+	// Checking PromQL expressions
+	for _, testCase := range tg.PromqlExprTests {
+		got, err := ruleTestQuery(suite.Context(), testCase.Expr, mint.Add(time.Duration(testCase.EvalTime)), suite.QueryEngine(), suite.Queryable())
+		if err != nil {
+			errs = append(errs, fail("expr %q, time %s: %s", testCase.Expr, testCase.EvalTime, err))
+			continue
+		}
+		gotSamples := make([]string, 0, len(got))
+		for _, s := range got {
+			gotSamples = append(gotSamples, formatTestSample(s.Metric, s.F, promqltest.HistogramTestExpression(s.H)))
+		}
+
+		expSamples := make([]string, 0, len(testCase.ExpSamples))
+		for _, s := range testCase.ExpSamples {
+			lbls, err := parser.ParseMetric(s.Labels)
+			var hist *histogram.FloatHistogram
+			if err == nil && s.Histogram != "" {
+				_, values, parseErr := parser.ParseSeriesDesc("{} " + s.Histogram)
+				switch {
+				case parseErr != nil:
+					err = parseErr
+				case len(values) != 1:
+					err = fmt.Errorf("expected 1 value, got %d", len(values))
+				case values[0].Histogram == nil:
+					err = fmt.Errorf("expected histogram, got %v", values[0])
+				default:
+					hist = values[0].Histogram
+				}
+			}
+			if err != nil {
+				errs = append(errs, fail("expr %q, time %s: labels %q: %s", testCase.Expr, testCase.EvalTime, s.Labels, err))
+				expSamples = nil
+				break
+			}
+			expSamples = append(expSamples, formatTestSample(lbls, s.Value, promqltest.HistogramTestExpression(hist)))
+		}
+		if expSamples == nil && len(testCase.ExpSamples) > 0 {
+			continue
+		}
+
+		sort.Strings(gotSamples)
+		sort.Strings(expSamples)
+		if strings.Join(expSamples, ", ") != strings.Join(gotSamples, ", ") {
+			errs = append(errs, fail("expr %q, time %s:\n  expected: %s\n  got: %s", testCase.Expr, testCase.EvalTime,
+				formatTestSamples(expSamples), formatTestSamples(gotSamples)))
+		}
+	}
+
+	return errs
+}
+
+// firingAlerts returns the firing alerts of the alerting rules with the name,
+// the same name can be used in several groups.
+func firingAlerts(groups []*rules.Group, alertname string) alertLabelsAndAnnotations {
+	var alerts alertLabelsAndAnnotations
+	for _, group := range groups {
+		for _, rule := range group.Rules() {
+			alertRule, ok := rule.(*rules.AlertingRule)
+			if !ok || alertRule.Name() != alertname {
+				continue
+			}
+			for _, alert := range alertRule.ActiveAlerts() {
+				if alert.State == rules.StateFiring {
+					alerts = append(alerts, alertLabelsAndAnnotation{
+						Labels:      alert.Labels.Copy(),
+						Annotations: alert.Annotations.Copy(),
+					})
+				}
+			}
+		}
+	}
+	return alerts
+}
+
+// seriesLoadingString returns the input series in PromQL test notation
+func (tg *ruleTestGroup) seriesLoadingString() string {
+	interval := tg.Interval.String()
+	interval = strings.TrimSuffix(interval, "0s")
+	interval = strings.TrimSuffix(interval, "0m")
+	if interval == "" {
+		interval = "0s"
+	}
+	result := fmt.Sprintf("load %v\n", interval)
+	for _, is := range tg.InputSeries {
+		result += fmt.Sprintf("  %v %v\n", is.Series, is.Values)
+	}
+	return result
+}
+
+// maxEvalTime returns the max eval time among all alert and PromQL tests
+func (tg *ruleTestGroup) maxEvalTime() time.Duration {
+	var maxd model.Duration
+	for _, alert := range tg.AlertRuleTests {
+		if alert.EvalTime > maxd {
+			maxd = alert.EvalTime
+		}
+	}
+	for _, pet := range tg.PromqlExprTests {
+		if pet.EvalTime > maxd {
+			maxd = pet.EvalTime
+		}
+	}
+	return time.Duration(maxd)
+}
+
+func ruleTestQuery(ctx context.Context, qs string, t time.Time, engine *promql.Engine, qu storage.Queryable) (promql.Vector, error) {
+	q, err := engine.NewInstantQuery(ctx, qu, nil, qs, t)
+	if err != nil {
+		return nil, err
+	}
+	res := q.Exec(ctx)
+	if res.Err != nil {
+		return nil, res.Err
+	}
+	switch v := res.Value.(type) {
+	case promql.Vector:
+		return v, nil
+	case promql.Scalar:
+		return promql.Vector{promql.Sample{T: v.T, F: v.V, Metric: labels.EmptyLabels()}}, nil
+	default:
+		return nil, errors.New("rule result is not a vector or scalar")
+	}
+}
+
+func formatTestSample(lbls labels.Labels, value float64, hist string) string {
+	if hist != "" {
+		return lbls.String() + " " + hist
+	}
+	return lbls.String() + " " + strconv.FormatFloat(value, 'E', -1, 64)
+}
+
+func formatTestSamples(samples []string) string {
+	if len(samples) == 0 {
+		return "nil"
+	}
+	return strings.Join(samples, ", ")
+}
+
+type alertLabelsAndAnnotation struct {
+	Labels      labels.Labels
+	Annotations labels.Labels
+}
+
+type alertLabelsAndAnnotations []alertLabelsAndAnnotation
+
+func (la alertLabelsAndAnnotations) Len() int      { return len(la) }
+func (la alertLabelsAndAnnotations) Swap(i, j int) { la[i], la[j] = la[j], la[i] }
+func (la alertLabelsAndAnnotations) Less(i, j int) bool {
+	if diff := labels.Compare(la[i].Labels, la[j].Labels); diff != 0 {
+		return diff < 0
+	}
+	return labels.Compare(la[i].Annotations, la[j].Annotations) < 0
+}
+
+func (la alertLabelsAndAnnotations) String() string {
+	if len(la) == 0 {
+		return "[]"
+	}
+	alerts := make([]string, 0, len(la))
+	for _, alert := range la {
+		alerts = append(alerts, "labels: "+alert.Labels.String()+" annotations: "+alert.Annotations.String())
+	}
+	return "[" + strings.Join(alerts, ", ") + "]"
+}
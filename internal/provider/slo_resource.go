@@ -0,0 +1,607 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/grafana/mimir/pkg/mimirtool/rules/rwrulefmt"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	sloDefaultTimeWindow = "30d"
+	sloDefaultAlertName  = "SLOErrorBudgetBurn"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var (
+	_ resource.Resource                   = &SLOResource{}
+	_ resource.ResourceWithModifyPlan     = &SLOResource{}
+	_ resource.ResourceWithValidateConfig = &SLOResource{}
+)
+
+// sloAlertingWindowAttributeTypes are the attributes of an alerting_windows element
+var sloAlertingWindowAttributeTypes = map[string]attr.Type{
+	"severity":     types.StringType,
+	"long_window":  types.StringType,
+	"short_window": types.StringType,
+	"burn_rate":    types.Float64Type,
+}
+
+func NewSLOResource() resource.Resource {
+	return &SLOResource{}
+}
+
+// SLOResource manages the recording rules and burn rate alerts of a service
+// level objective as rule groups of a Grafana Mimir namespace.
+type SLOResource struct {
+	// client is the client of the Grafana Mimir ruler API
+	client mimirClientInterface
+	// defaultRuleLabels are the labels merged into every rule, from the provider configuration
+	defaultRuleLabels map[string]string
+	// rulerLimits are the limits of the tenant, nil when the provider is not configured
+	rulerLimits *rulerLimitsSource
+}
+
+// SLOResourceModel describes the resource data model.
+type SLOResourceModel struct {
+	ID              types.String   `tfsdk:"id"`
+	Namespace       types.String   `tfsdk:"namespace"`
+	Name            types.String   `tfsdk:"name"`
+	GoodQuery       types.String   `tfsdk:"good_query"`
+	TotalQuery      types.String   `tfsdk:"total_query"`
+	ErrorRatioQuery types.String   `tfsdk:"error_ratio_query"`
+	Objective       types.Float64  `tfsdk:"objective"`
+	TimeWindow      types.String   `tfsdk:"time_window"`
+	AlertName       types.String   `tfsdk:"alert_name"`
+	AlertingWindows types.List     `tfsdk:"alerting_windows"`
+	Labels          types.Map      `tfsdk:"labels"`
+	Annotations     types.Map      `tfsdk:"annotations"`
+	ConfigYAML      types.String   `tfsdk:"config_yaml"`
+	Timeouts        timeouts.Value `tfsdk:"timeouts"`
+}
+
+// sloAlertingWindowModel is an element of alerting_windows
+type sloAlertingWindowModel struct {
+	Severity    types.String  `tfsdk:"severity"`
+	LongWindow  types.String  `tfsdk:"long_window"`
+	ShortWindow types.String  `tfsdk:"short_window"`
+	BurnRate    types.Float64 `tfsdk:"burn_rate"`
+}
+
+func (r *SLOResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_slo"
+}
+
+func (r *SLOResource) Schema(ctx context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	tflog.Debug(ctx, "SCHEMA - init")
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Generates and manages the recording rules and the [multiwindow, multi-burn-rate alerts](https://sre.google/workbook/alerting-on-slos/) " +
+			"of a service level objective in a Grafana Mimir namespace. The rule groups are named after the SLO (`<name>-sli-recordings`, `<name>-meta-recordings` " +
+			"and `<name>-alerts`), the other rule groups of the namespace are left untouched so that several SLOs can share a namespace. " +
+			"The recording rules follow the `level:metric:operations` naming convention and carry an `slo` label set to the name of the SLO.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "hash",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"namespace": schema.StringAttribute{
+				MarkdownDescription: "The namespace of the rule groups in Grafana Mimir. Renaming is done in place: the rule groups are created in the new namespace before they are deleted from the previous one.",
+				Required:            true,
+			},
+			"name": schema.StringAttribute{
+				MarkdownDescription: "The name of the SLO, used as the value of the `slo` label of the rules and as the prefix of the rule group names.",
+				Required:            true,
+			},
+			"good_query": schema.StringAttribute{
+				MarkdownDescription: "PromQL query of the rate of good events, using `" + sloWindowPlaceholder + "` as the range of its range vector selectors, " +
+					"e.g. `sum(rate(http_requests_total{code!~\"5..\"}[" + sloWindowPlaceholder + "]))`. Requires `total_query`.",
+				Optional: true,
+			},
+			"total_query": schema.StringAttribute{
+				MarkdownDescription: "PromQL query of the rate of all events, using `" + sloWindowPlaceholder + "` as the range of its range vector selectors. Requires `good_query`.",
+				Optional:            true,
+			},
+			"error_ratio_query": schema.StringAttribute{
+				MarkdownDescription: "PromQL query of the ratio of bad events, between 0 and 1, using `" + sloWindowPlaceholder + "` as the range of its range vector selectors. " +
+					"Either `error_ratio_query` or both `good_query` and `total_query` must be set.",
+				Optional: true,
+			},
+			"objective": schema.Float64Attribute{
+				MarkdownDescription: "The target percentage of good events over the time window, e.g. `99.9`.",
+				Required:            true,
+			},
+			"time_window": schema.StringAttribute{
+				MarkdownDescription: "The period over which the objective is evaluated, as a Prometheus duration (default `" + sloDefaultTimeWindow + "`).",
+				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString(sloDefaultTimeWindow),
+				Validators: []validator.String{
+					durationValidator{},
+				},
+			},
+			"alert_name": schema.StringAttribute{
+				MarkdownDescription: "The name of the generated alerts, one per severity (default `" + sloDefaultAlertName + "`).",
+				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString(sloDefaultAlertName),
+			},
+			"alerting_windows": schema.ListNestedAttribute{
+				MarkdownDescription: "The burn rate alerts: an alert fires when the error budget burns faster than `burn_rate` over both windows. " +
+					"The windows of a severity are combined into a single alert. Defaults to the windows of the Google SRE workbook: " +
+					"`critical` when 2% of the budget is consumed in 1h (checked over 5m) or 5% in 6h (checked over 30m), " +
+					"`warning` when 10% of the budget is consumed in 1d (checked over 2h) or in 3d (checked over 6h), with burn rates derived from `time_window`. The default windows longer than `time_window` are left out.",
+				Optional: true,
+				Computed: true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"severity": schema.StringAttribute{
+							MarkdownDescription: "The value of the `severity` label of the alert.",
+							Required:            true,
+						},
+						"long_window": schema.StringAttribute{
+							MarkdownDescription: "The window over which the burn rate is measured, e.g. `1h`.",
+							Required:            true,
+							Validators: []validator.String{
+								durationValidator{},
+							},
+						},
+						"short_window": schema.StringAttribute{
+							MarkdownDescription: "The window confirming that the budget is still burning, so that the alert resolves quickly, e.g. `5m`.",
+							Required:            true,
+							Validators: []validator.String{
+								durationValidator{},
+							},
+						},
+						"burn_rate": schema.Float64Attribute{
+							MarkdownDescription: "The rate at which the error budget is consumed relative to the time window, e.g. `14.4`.",
+							Required:            true,
+						},
+					},
+				},
+			},
+			"labels": schema.MapAttribute{
+				MarkdownDescription: "Labels added to every generated rule. They take precedence over the provider `default_rule_labels`.",
+				ElementType:         types.StringType,
+				Optional:            true,
+				Validators: []validator.Map{
+					labelNamesMapValidator{},
+				},
+			},
+			"annotations": schema.MapAttribute{
+				MarkdownDescription: "Annotations added to the generated alerts, e.g. `runbook_url`. `summary` replaces the generated one.",
+				ElementType:         types.StringType,
+				Optional:            true,
+				Validators: []validator.Map{
+					labelNamesMapValidator{},
+				},
+			},
+			"config_yaml": schema.StringAttribute{
+				MarkdownDescription: "The generated rule groups as namespace YAML. It is refreshed from Grafana Mimir, so that changes made outside of Terraform are reverted.",
+				Computed:            true,
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeouts.Block(ctx, timeoutsOpts),
+		},
+	}
+}
+
+func (r *SLOResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	tflog.Debug(ctx, "CONFIGURE - init")
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	c, ok := req.ProviderData.(*myClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *myClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	r.client = c.rulerClients[rulerBackendMimir]
+	r.defaultRuleLabels = c.defaultRuleLabels
+	r.rulerLimits = c.rulerLimits
+}
+
+func (r *SLOResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var config SLOResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if config.TimeWindow.IsNull() {
+		config.TimeWindow = types.StringValue(sloDefaultTimeWindow)
+	}
+	spec, known := config.slo(ctx, nil, &resp.Diagnostics)
+	if !known || resp.Diagnostics.HasError() {
+		// Checked again at plan time once the values are known
+		return
+	}
+	for _, err := range spec.validate() {
+		resp.Diagnostics.AddError("Invalid Service Level Objective", err.Error())
+	}
+}
+
+// ModifyPlan fills the default alerting windows and renders the rule groups
+// in config_yaml, so that plans show the rules about to be written, and checks
+// them against the ruler limits.
+func (r *SLOResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		// Nothing to check on destroy
+		return
+	}
+
+	var plan, config SLOResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var state SLOResourceModel
+	if !req.State.Raw.IsNull() {
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		// The ID is derived from the namespace and the name
+		if !plan.Namespace.Equal(state.Namespace) || !plan.Name.Equal(state.Name) {
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("id"), types.StringUnknown())...)
+		}
+	}
+
+	if config.AlertingWindows.IsNull() {
+		// The default burn rates depend on the time window
+		plan.AlertingWindows = types.ListUnknown(plan.AlertingWindows.ElementType(ctx))
+		if timeWindow, err := model.ParseDuration(plan.TimeWindow.ValueString()); err == nil && !plan.TimeWindow.IsUnknown() {
+			var diags diag.Diagnostics
+			plan.AlertingWindows, diags = sloAlertingWindowsValue(defaultSLOAlertingWindows(timeWindow))
+			resp.Diagnostics.Append(diags...)
+		}
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("alerting_windows"), plan.AlertingWindows)...)
+	}
+
+	groups, configYAML, known := plan.renderRuleGroups(ctx, r.defaultRuleLabels, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	value := types.StringUnknown()
+	if known {
+		value = types.StringValue(configYAML)
+	}
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("config_yaml"), value)...)
+
+	// Catch the limits an apply would hit half-way, only when the rule groups are written
+	changed := req.State.Raw.IsNull() || !plan.Namespace.Equal(state.Namespace) || !value.Equal(state.ConfigYAML)
+	if known && changed && !plan.Namespace.IsUnknown() {
+		owned := ruleGroupNames(groups)
+		if !req.State.Raw.IsNull() {
+			for name := range state.ownedRuleGroups() {
+				owned[name] = true
+			}
+		}
+		checkRulerNamespaceLimits(ctx, r.rulerLimits, r.client, plan.Namespace.ValueString(), groups, owned, &resp.Diagnostics)
+	}
+}
+
+func (r *SLOResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	tflog.Debug(ctx, "CREATE - init")
+	var plan SLOResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	createTimeout, diags := plan.Timeouts.Create(ctx, defaultTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, createTimeout)
+	defer cancel()
+
+	namespace := plan.Namespace.ValueString()
+	groups, configYAML, _ := plan.renderRuleGroups(ctx, r.defaultRuleLabels, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	owned := ruleGroupNames(groups)
+
+	// Refuse to clobber rule groups managed elsewhere
+	if !checkSLORuleGroupsAreFree(ctx, r.client, namespace, owned, &resp.Diagnostics) {
+		return
+	}
+	if !checkRulerNamespaceLimits(ctx, r.rulerLimits, r.client, namespace, groups, owned, &resp.Diagnostics) {
+		return
+	}
+	if !applyRuleGroups(ctx, r.client, namespace, groups, owned, &resp.Diagnostics) {
+		return
+	}
+
+	// Changes made in Grafana Mimir afterwards show up on refresh
+	plan.ID = types.StringValue(plan.id())
+	plan.ConfigYAML = types.StringValue(configYAML)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *SLOResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	tflog.Debug(ctx, "READ - init")
+	var state SLOResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	readTimeout, diags := state.Timeouts.Read(ctx, defaultTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	namespace := state.Namespace.ValueString()
+	groups, _, err := listRemoteRuleGroups(ctx, r.client, namespace)
+	if err != nil {
+		addAPIErrorDiagnostic(&resp.Diagnostics, fmt.Sprintf("read the rule groups of namespace %q", namespace), err)
+		return
+	}
+	groups = filterOwnedRuleGroups(groups, state.ownedRuleGroups())
+	if len(groups) == 0 {
+		tflog.Info(ctx, "No rule groups of the SLO found in backend; removing from state", map[string]interface{}{"namespace": namespace, "slo": state.Name.ValueString()})
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	normalized, ok := normalizeRemoteRuleGroups(ctx, sortRuleGroups(groups), rulerBackendMimir, "READ", &resp.Diagnostics)
+	if !ok {
+		return
+	}
+	state.ConfigYAML = types.StringValue(normalized)
+	state.ID = types.StringValue(state.id())
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+func (r *SLOResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	tflog.Debug(ctx, "UPDATE - init")
+	var plan, state SLOResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	updateTimeout, diags := plan.Timeouts.Update(ctx, defaultTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, updateTimeout)
+	defer cancel()
+
+	namespace := plan.Namespace.ValueString()
+	groups, configYAML, _ := plan.renderRuleGroups(ctx, r.defaultRuleLabels, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// The groups of the previous name of the SLO are deleted
+	owned := ruleGroupNames(groups)
+	for name := range state.ownedRuleGroups() {
+		owned[name] = true
+	}
+
+	if !checkRulerNamespaceLimits(ctx, r.rulerLimits, r.client, namespace, groups, owned, &resp.Diagnostics) {
+		return
+	}
+
+	// A renamed SLO must not take over the rule groups of another one
+	previousNamespace := state.Namespace.ValueString()
+	if (previousNamespace != namespace || !plan.Name.Equal(state.Name)) &&
+		!checkSLORuleGroupsAreFree(ctx, r.client, namespace, ruleGroupNames(groups), &resp.Diagnostics) {
+		return
+	}
+
+	if previousNamespace != namespace {
+		// Renamed: the previous namespace is only cleaned up once the new one is live
+		if !renameNamespace(ctx, r.client, previousNamespace, namespace, groups, owned, &resp.Diagnostics) {
+			return
+		}
+	} else if !applyRuleGroups(ctx, r.client, namespace, groups, owned, &resp.Diagnostics) {
+		return
+	}
+
+	plan.ID = types.StringValue(plan.id())
+	plan.ConfigYAML = types.StringValue(configYAML)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *SLOResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	tflog.Debug(ctx, "DELETE - init")
+	var state SLOResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	deleteTimeout, diags := state.Timeouts.Delete(ctx, defaultTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, deleteTimeout)
+	defer cancel()
+
+	namespace := state.Namespace.ValueString()
+	if err := deleteOwnedRuleGroups(ctx, r.client, namespace, state.ownedRuleGroups()); err != nil {
+		addAPIErrorDiagnostic(&resp.Diagnostics, fmt.Sprintf("delete the rule groups of SLO %q in namespace %q", state.Name.ValueString(), namespace), err)
+	}
+}
+
+func (m SLOResourceModel) id() string {
+	return hash(m.Namespace.ValueString() + "/" + m.Name.ValueString())
+}
+
+// ownedRuleGroups returns the names of the rule groups generated for the SLO
+func (m SLOResourceModel) ownedRuleGroups() map[string]bool {
+	return slo{Name: m.Name.ValueString()}.ruleGroupNames()
+}
+
+// slo converts the model to the SLO the rules are generated for, known is
+// false when one of the values is not known yet.
+func (m SLOResourceModel) slo(ctx context.Context, defaultLabels map[string]string, diagnostics *diag.Diagnostics) (slo, bool) {
+	for _, value := range []attr.Value{m.Name, m.GoodQuery, m.TotalQuery, m.ErrorRatioQuery, m.Objective, m.TimeWindow, m.AlertName, m.AlertingWindows, m.Labels, m.Annotations} {
+		if value.IsUnknown() {
+			return slo{}, false
+		}
+	}
+
+	timeWindow, err := model.ParseDuration(m.TimeWindow.ValueString())
+	if err != nil {
+		diagnostics.AddAttributeError(path.Root("time_window"), "Invalid duration", err.Error())
+		return slo{}, false
+	}
+	spec := slo{
+		Name:            m.Name.ValueString(),
+		GoodQuery:       m.GoodQuery.ValueString(),
+		TotalQuery:      m.TotalQuery.ValueString(),
+		ErrorRatioQuery: m.ErrorRatioQuery.ValueString(),
+		Objective:       m.Objective.ValueFloat64(),
+		TimeWindow:      timeWindow,
+		AlertName:       m.AlertName.ValueString(),
+		Labels:          mapStringFromTypesMap(m.Labels),
+		Annotations:     mapStringFromTypesMap(m.Annotations),
+		DefaultLabels:   defaultLabels,
+	}
+	if spec.AlertName == "" {
+		spec.AlertName = sloDefaultAlertName
+	}
+	if m.AlertingWindows.IsNull() {
+		spec.AlertingWindows = defaultSLOAlertingWindows(timeWindow)
+		return spec, true
+	}
+
+	var windows []sloAlertingWindowModel
+	diagnostics.Append(m.AlertingWindows.ElementsAs(ctx, &windows, false)...)
+	if diagnostics.HasError() {
+		return slo{}, false
+	}
+	for i, w := range windows {
+		if w.Severity.IsUnknown() || w.LongWindow.IsUnknown() || w.ShortWindow.IsUnknown() || w.BurnRate.IsUnknown() {
+			return slo{}, false
+		}
+		window := sloAlertingWindow{Severity: w.Severity.ValueString(), BurnRate: w.BurnRate.ValueFloat64()}
+		for _, d := range []struct {
+			attribute string
+			value     types.String
+			out       *model.Duration
+		}{
+			{"long_window", w.LongWindow, &window.LongWindow},
+			{"short_window", w.ShortWindow, &window.ShortWindow},
+		} {
+			if *d.out, err = model.ParseDuration(d.value.ValueString()); err != nil {
+				diagnostics.AddAttributeError(path.Root("alerting_windows").AtListIndex(i).AtName(d.attribute), "Invalid duration", err.Error())
+			}
+		}
+		spec.AlertingWindows = append(spec.AlertingWindows, window)
+	}
+	return spec, !diagnostics.HasError()
+}
+
+// renderRuleGroups generates the rule groups of the SLO and renders them as
+// normalized namespace YAML, known is false when one of the values is not
+// known yet.
+func (m SLOResourceModel) renderRuleGroups(ctx context.Context, defaultLabels map[string]string, diagnostics *diag.Diagnostics) ([]rwrulefmt.RuleGroup, string, bool) {
+	spec, known := m.slo(ctx, defaultLabels, diagnostics)
+	if !known {
+		return nil, "", false
+	}
+	groups, err := spec.ruleGroups()
+	if err != nil {
+		diagnostics.AddError("Invalid Service Level Objective", err.Error())
+		return nil, "", false
+	}
+	configYAML, ok := normalizeRemoteRuleGroups(ctx, sortRuleGroups(groups), rulerBackendMimir, "PLAN", diagnostics)
+	return groups, configYAML, ok
+}
+
+// sloAlertingWindowsValue converts the alerting windows to the alerting_windows attribute
+func sloAlertingWindowsValue(windows []sloAlertingWindow) (types.List, diag.Diagnostics) {
+	elementType := types.ObjectType{AttrTypes: sloAlertingWindowAttributeTypes}
+	var diags diag.Diagnostics
+	elements := make([]attr.Value, 0, len(windows))
+	for _, w := range windows {
+		element, d := types.ObjectValue(sloAlertingWindowAttributeTypes, map[string]attr.Value{
+			"severity":     types.StringValue(w.Severity),
+			"long_window":  types.StringValue(w.LongWindow.String()),
+			"short_window": types.StringValue(w.ShortWindow.String()),
+			"burn_rate":    types.Float64Value(w.BurnRate),
+		})
+		diags.Append(d...)
+		elements = append(elements, element)
+	}
+	if diags.HasError() {
+		return types.ListUnknown(elementType), diags
+	}
+	list, d := types.ListValue(elementType, elements)
+	diags.Append(d...)
+	return list, diags
+}
+
+// sortRuleGroups sorts the groups by name, the order in which Grafana Mimir lists them
+func sortRuleGroups(groups []rwrulefmt.RuleGroup) []rwrulefmt.RuleGroup {
+	sorted := slices.Clone(groups)
+	slices.SortFunc(sorted, func(a, b rwrulefmt.RuleGroup) int { return strings.Compare(a.Name, b.Name) })
+	return sorted
+}
+
+// checkSLORuleGroupsAreFree reports an error if the namespace already contains
+// rule groups named like the groups of the SLO, e.g. an SLO of the same name.
+func checkSLORuleGroupsAreFree(ctx context.Context, client mimirClientInterface, namespace string, owned map[string]bool, diagnostics *diag.Diagnostics) bool {
+	groups, _, err := listRemoteRuleGroups(ctx, client, namespace)
+	if err != nil {
+		addAPIErrorDiagnostic(diagnostics, fmt.Sprintf("check the rule groups of namespace %q", namespace), err)
+		return false
+	}
+	groups = filterOwnedRuleGroups(groups, owned)
+	if len(groups) == 0 {
+		return true
+	}
+
+	names := make([]string, 0, len(groups))
+	for _, group := range sortRuleGroups(groups) {
+		names = append(names, group.Name)
+	}
+	diagnostics.AddAttributeError(
+		path.Root("name"),
+		"Rule Groups Already Exist",
+		fmt.Sprintf("Namespace %q already contains the rule groups of the SLO (%s), probably managed by another SLO of the same name or by hand. "+
+			"Rename the SLO or delete these rule groups.", namespace, strings.Join(names, ", ")),
+	)
+	return false
+}
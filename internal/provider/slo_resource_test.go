@@ -0,0 +1,335 @@
+package provider
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/mimir/pkg/mimirtool/rules/rwrulefmt"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/promql/parser"
+	"gopkg.in/yaml.v3"
+)
+
+func testSLO() slo {
+	timeWindow := model.Duration(30 * 24 * time.Hour)
+	return slo{
+		Name:            "api-availability",
+		GoodQuery:       `sum(rate(http_requests_total{job="api",code!~"5.."}[$window]))`,
+		TotalQuery:      `sum(rate(http_requests_total{job="api"}[$window]))`,
+		Objective:       99.9,
+		TimeWindow:      timeWindow,
+		AlertName:       sloDefaultAlertName,
+		AlertingWindows: defaultSLOAlertingWindows(timeWindow),
+		Labels:          map[string]string{"team": "api"},
+		Annotations:     map[string]string{"runbook_url": "https://runbooks.example.com/api"},
+		DefaultLabels:   map[string]string{"team": "platform", "env": "prod"},
+	}
+}
+
+func TestDefaultSLOAlertingWindows(t *testing.T) {
+	for timeWindow, want := range map[time.Duration][]float64{
+		30 * 24 * time.Hour: {14.4, 6, 3, 1},
+		28 * 24 * time.Hour: {13.44, 5.6, 2.8, 0.9333},
+		// The 3d window is left out
+		24 * time.Hour: {0.48, 0.2, 0.1},
+	} {
+		windows := defaultSLOAlertingWindows(model.Duration(timeWindow))
+		if len(windows) != len(want) {
+			t.Fatalf("time window %s: expected %d windows, got %d", model.Duration(timeWindow), len(want), len(windows))
+		}
+		for i, w := range windows {
+			if w.BurnRate != want[i] {
+				t.Errorf("time window %s: window %d: expected burn rate %v, got %v", model.Duration(timeWindow), i, want[i], w.BurnRate)
+			}
+		}
+	}
+}
+
+func TestSLORuleGroups(t *testing.T) {
+	groups, err := testSLO().ruleGroups()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	content, err := yaml.Marshal(map[string]any{"groups": groups})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ruleNamespace, err := getRuleNamespaceFromYAML(context.Background(), string(content), rulerBackendMimir)
+	if err != nil {
+		t.Fatalf("the generated rules are not a valid namespace: %s", err)
+	}
+	if issues := findRecordingRuleIssues(ruleNamespace, true); len(issues) > 0 {
+		t.Errorf("the generated recording rules don't follow the naming convention: %+v", issues)
+	}
+
+	records := map[string]bool{}
+	var alerts []string
+	for _, group := range ruleNamespace.Groups {
+		for _, rule := range group.Rules {
+			if _, err := parser.ParseExpr(rule.Expr.Value); err != nil {
+				t.Errorf("rule %s%s: invalid expression: %s", rule.Record.Value, rule.Alert.Value, err)
+			}
+			if rule.Labels["slo"] != "api-availability" || rule.Labels["team"] != "api" || rule.Labels["env"] != "prod" {
+				t.Errorf("rule %s%s: unexpected labels %v", rule.Record.Value, rule.Alert.Value, rule.Labels)
+			}
+			if rule.Record.Value != "" {
+				records[rule.Record.Value] = true
+				continue
+			}
+			alerts = append(alerts, rule.Labels["severity"])
+			if rule.Annotations["runbook_url"] == "" || rule.Annotations["summary"] == "" {
+				t.Errorf("alert %s: unexpected annotations %v", rule.Alert.Value, rule.Annotations)
+			}
+		}
+	}
+	for _, record := range []string{
+		"slo:sli_error:ratio_rate5m", "slo:sli_error:ratio_rate30m", "slo:sli_error:ratio_rate1h", "slo:sli_error:ratio_rate2h",
+		"slo:sli_error:ratio_rate6h", "slo:sli_error:ratio_rate1d", "slo:sli_error:ratio_rate3d", "slo:sli_error:ratio_rate30d",
+		"slo:objective:ratio", "slo:error_budget:ratio", "slo:time_period:days", "slo:current_burn_rate:ratio",
+		"slo:period_burn_rate:ratio", "slo:period_error_budget_remaining:ratio",
+	} {
+		if !records[record] {
+			t.Errorf("expected recording rule %s", record)
+		}
+	}
+	if len(records) != 14 {
+		t.Errorf("expected 14 recording rules, got %d: %v", len(records), records)
+	}
+	if strings.Join(alerts, ",") != "critical,warning" {
+		t.Errorf("expected one alert per severity, got %v", alerts)
+	}
+	if expr := ruleNamespace.Groups[2].Rules[0].Expr.Value; !strings.Contains(expr, "slo:sli_error:ratio_rate1h{slo=\"api-availability\"} > (14.4 * (1 - 0.999))") {
+		t.Errorf("unexpected critical alert expression:\n%s", expr)
+	}
+}
+
+func TestSLOValidate(t *testing.T) {
+	for name, tc := range map[string]struct {
+		update func(*slo)
+		err    string
+	}{
+		"valid":              {update: func(*slo) {}},
+		"error ratio query":  {update: func(s *slo) { s.GoodQuery, s.TotalQuery, s.ErrorRatioQuery = "", "", "avg(rate(errors[$window]))" }},
+		"no query":           {update: func(s *slo) { s.GoodQuery, s.TotalQuery = "", "" }, err: "either error_ratio_query or both good_query and total_query must be set"},
+		"both queries":       {update: func(s *slo) { s.ErrorRatioQuery = "avg(rate(errors[$window]))" }, err: "error_ratio_query can't be used"},
+		"missing window":     {update: func(s *slo) { s.TotalQuery = "sum(rate(http_requests_total[5m]))" }, err: "total_query must use $window"},
+		"invalid query":      {update: func(s *slo) { s.GoodQuery = "sum(rate(http_requests_total[$window])" }, err: "good_query is not a valid PromQL expression"},
+		"objective too high": {update: func(s *slo) { s.Objective = 100 }, err: "the objective must be a percentage between 0 and 100 excluded, got 100"},
+		"inverted windows": {
+			update: func(s *slo) { s.AlertingWindows[0].ShortWindow = model.Duration(2 * time.Hour) },
+			err:    "alerting window 0: the short window (2h) must be shorter than the long window (1h)",
+		},
+		"default windows of a short time window": {
+			update: func(s *slo) {
+				s.TimeWindow = model.Duration(24 * time.Hour)
+				s.AlertingWindows = defaultSLOAlertingWindows(s.TimeWindow)
+			},
+		},
+		"window exceeding the time window": {
+			update: func(s *slo) { s.TimeWindow = model.Duration(24 * time.Hour) },
+			err:    "alerting window 3: the long window (3d) must not exceed the time window (1d)",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			spec := testSLO()
+			tc.update(&spec)
+			errs := spec.validate()
+			if tc.err == "" {
+				if len(errs) > 0 {
+					t.Fatalf("unexpected errors: %v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || !strings.Contains(errs[0].Error(), tc.err) {
+				t.Fatalf("expected an error containing %q, got %v", tc.err, errs)
+			}
+		})
+	}
+}
+
+func TestSLORuleGroupsSharedNamespace(t *testing.T) {
+	ctx := context.Background()
+	client := newFakeMimirClient()
+	client.namespaces["slos"] = map[string]rwrulefmt.RuleGroup{"manual": testRuleGroup("manual", 0)}
+
+	availability := testSLO()
+	latency := testSLO()
+	latency.Name = "api-latency"
+	for _, spec := range []slo{availability, latency} {
+		groups, err := spec.ruleGroups()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var diags diag.Diagnostics
+		if !checkSLORuleGroupsAreFree(ctx, client, "slos", spec.ruleGroupNames(), &diags) ||
+			!applyRuleGroups(ctx, client, "slos", groups, spec.ruleGroupNames(), &diags) {
+			t.Fatalf("unexpected diagnostics: %v", diags)
+		}
+	}
+	if got := strings.Join(client.groupNames("slos"), ","); got != "api-availability-alerts,api-availability-meta-recordings,api-availability-sli-recordings,"+
+		"api-latency-alerts,api-latency-meta-recordings,api-latency-sli-recordings,manual" {
+		t.Fatalf("unexpected groups: %s", got)
+	}
+
+	var diags diag.Diagnostics
+	if checkSLORuleGroupsAreFree(ctx, client, "slos", latency.ruleGroupNames(), &diags) {
+		t.Fatal("expected the rule groups of an SLO of the same name to be reported")
+	}
+
+	if err := deleteOwnedRuleGroups(ctx, client, "slos", availability.ruleGroupNames()); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(client.groupNames("slos"), ","); got != "api-latency-alerts,api-latency-meta-recordings,api-latency-sli-recordings,manual" {
+		t.Fatalf("unexpected groups after delete: %s", got)
+	}
+}
+
+// testSLOResourceModel returns the planned model of the test SLO with the default alerting windows
+func testSLOResourceModel(namespace, name string) *SLOResourceModel {
+	return &SLOResourceModel{
+		ID:              types.StringUnknown(),
+		Namespace:       types.StringValue(namespace),
+		Name:            types.StringValue(name),
+		GoodQuery:       types.StringValue(testSLO().GoodQuery),
+		TotalQuery:      types.StringValue(testSLO().TotalQuery),
+		ErrorRatioQuery: types.StringNull(),
+		Objective:       types.Float64Value(99.9),
+		TimeWindow:      types.StringValue(sloDefaultTimeWindow),
+		AlertName:       types.StringValue(sloDefaultAlertName),
+		AlertingWindows: types.ListNull(types.ObjectType{AttrTypes: sloAlertingWindowAttributeTypes}),
+		Labels:          types.MapNull(types.StringType),
+		Annotations:     types.MapNull(types.StringType),
+		ConfigYAML:      types.StringUnknown(),
+		Timeouts:        nullTimeouts(),
+	}
+}
+
+func TestSLOUpdateRenameOntoAnotherSLO(t *testing.T) {
+	ctx := context.Background()
+	client := newFakeMimirClient()
+	r := &SLOResource{client: client}
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+	null := tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil)
+
+	// Both SLOs share the namespace
+	for _, name := range []string{"api-availability", "api-latency"} {
+		spec := testSLO()
+		spec.Name = name
+		groups, err := spec.ruleGroups()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, group := range groups {
+			_ = client.CreateRuleGroup(ctx, "slos", group)
+		}
+	}
+	before := client.namespaces["slos"]["api-latency-alerts"]
+
+	state := tfsdk.State{Schema: schemaResp.Schema, Raw: null}
+	stateModel := testSLOResourceModel("slos", "api-availability")
+	stateModel.ID, stateModel.ConfigYAML = types.StringValue("id"), types.StringValue("")
+	plan := tfsdk.Plan{Schema: schemaResp.Schema, Raw: null}
+	diags := state.Set(ctx, stateModel)
+	diags.Append(plan.Set(ctx, testSLOResourceModel("slos", "api-latency"))...)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	resp := &fwresource.UpdateResponse{State: state}
+	r.Update(ctx, fwresource.UpdateRequest{Plan: plan, State: state}, resp)
+	if resp.Diagnostics.ErrorsCount() != 1 || resp.Diagnostics[0].Summary() != "Rule Groups Already Exist" {
+		t.Fatalf("expected the rule groups of the other SLO to be reported, got: %v", resp.Diagnostics)
+	}
+	if len(client.groupNames("slos")) != 6 || !reflect.DeepEqual(client.namespaces["slos"]["api-latency-alerts"], before) {
+		t.Fatalf("expected the rule groups to be left untouched, got %v", client.groupNames("slos"))
+	}
+}
+
+func TestSLOModifyPlanRulerLimits(t *testing.T) {
+	ctx := context.Background()
+	client := newFakeMimirClient()
+	for _, group := range []string{"a", "b"} {
+		_ = client.CreateRuleGroup(ctx, "other", testRuleGroup(group, 0))
+	}
+	r := &SLOResource{client: client, rulerLimits: &rulerLimitsSource{configured: &rulerLimitsModel{
+		MaxRuleGroupsPerTenant: types.Int64Value(4),
+	}}}
+
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+	null := tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil)
+	plan := tfsdk.Plan{Schema: schemaResp.Schema, Raw: null}
+	if diags := plan.Set(ctx, testSLOResourceModel("slos", "api-availability")); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	// The 3 rule groups of the SLO and the 2 of the other namespace exceed the limit of 4
+	resp := &fwresource.ModifyPlanResponse{Plan: plan}
+	r.ModifyPlan(ctx, fwresource.ModifyPlanRequest{
+		Plan:   plan,
+		Config: tfsdk.Config{Schema: schemaResp.Schema, Raw: plan.Raw},
+		State:  tfsdk.State{Schema: schemaResp.Schema, Raw: null},
+	}, resp)
+	if resp.Diagnostics.ErrorsCount() != 1 || !strings.Contains(resp.Diagnostics[0].Detail(), "the tenant would have 5 rule groups") {
+		t.Fatalf("expected the tenant limit to be exceeded at plan time, got: %v", resp.Diagnostics)
+	}
+}
+
+func TestAccResourceSLO(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceSLO,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("mimirtool_slo.api", "alerting_windows.#", "4"),
+					resource.TestCheckResourceAttr("mimirtool_slo.api", "alerting_windows.0.burn_rate", "14.4"),
+					resource.TestCheckResourceAttrSet("mimirtool_slo.api", "config_yaml"),
+				),
+			},
+			{
+				Config:      testAccResourceSLOInvalid,
+				ExpectError: regexp.MustCompile(`total_query must use \$window`),
+			},
+		},
+	})
+}
+
+const testAccResourceSLO = `
+provider "mimirtool" {
+  address = "http://localhost:8080"
+}
+
+resource "mimirtool_slo" "api" {
+  namespace   = "slos"
+  name        = "api-availability"
+  good_query  = "sum(rate(http_requests_total{job=\"api\",code!~\"5..\"}[$window]))"
+  total_query = "sum(rate(http_requests_total{job=\"api\"}[$window]))"
+  objective   = 99.9
+}
+`
+
+const testAccResourceSLOInvalid = `
+provider "mimirtool" {
+  address = "http://localhost:8080"
+}
+
+resource "mimirtool_slo" "api" {
+  namespace   = "slos"
+  name        = "api-availability"
+  good_query  = "sum(rate(http_requests_total{job=\"api\",code!~\"5..\"}[$window]))"
+  total_query = "sum(rate(http_requests_total{job=\"api\"}[5m]))"
+  objective   = 99.9
+}
+`
@@ -0,0 +1,263 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/mimir/pkg/mimirtool/rules/rwrulefmt"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/rulefmt"
+	"github.com/prometheus/prometheus/promql/parser"
+	"gopkg.in/yaml.v3"
+)
+
+// sloWindowPlaceholder is replaced by the range of the window in the SLI queries
+const sloWindowPlaceholder = "$window"
+
+// sloBaseWindow is the shortest window of the SLI, the error ratio over the
+// time window is averaged from it
+var sloBaseWindow = model.Duration(5 * time.Minute)
+
+// sloDefaultAlertingWindows are the multiwindow, multi-burn-rate alerts of the
+// Google SRE workbook, by share of the error budget consumed over the long
+// window. See: https://sre.google/workbook/alerting-on-slos/
+var sloDefaultAlertingWindows = []struct {
+	severity          string
+	long, short       model.Duration
+	budgetConsumption float64
+}{
+	{"critical", model.Duration(time.Hour), model.Duration(5 * time.Minute), 0.02},
+	{"critical", model.Duration(6 * time.Hour), model.Duration(30 * time.Minute), 0.05},
+	{"warning", model.Duration(24 * time.Hour), model.Duration(2 * time.Hour), 0.1},
+	{"warning", model.Duration(72 * time.Hour), model.Duration(6 * time.Hour), 0.1},
+}
+
+// slo describes the service level objective the rules are generated for
+type slo struct {
+	Name string
+	// Either ErrorRatioQuery or both GoodQuery and TotalQuery are set, they
+	// use sloWindowPlaceholder as the range of their range vector selectors
+	GoodQuery       string
+	TotalQuery      string
+	ErrorRatioQuery string
+	// Objective is the target percentage of good events, e.g. 99.9
+	Objective       float64
+	TimeWindow      model.Duration
+	AlertName       string
+	AlertingWindows []sloAlertingWindow
+	Labels          map[string]string
+	Annotations     map[string]string
+	// DefaultLabels are merged into the labels of every rule, Labels take precedence
+	DefaultLabels map[string]string
+}
+
+// sloAlertingWindow fires an alert when the error budget burns faster than
+// BurnRate over both windows
+type sloAlertingWindow struct {
+	Severity    string
+	LongWindow  model.Duration
+	ShortWindow model.Duration
+	BurnRate    float64
+}
+
+// defaultSLOAlertingWindows returns the default alerting windows, with burn
+// rates consuming the same share of the error budget of the time window. The
+// windows longer than the time window are left out.
+func defaultSLOAlertingWindows(timeWindow model.Duration) []sloAlertingWindow {
+	windows := make([]sloAlertingWindow, 0, len(sloDefaultAlertingWindows))
+	for _, w := range sloDefaultAlertingWindows {
+		if w.long > timeWindow {
+			continue
+		}
+		burnRate := w.budgetConsumption * float64(timeWindow) / float64(w.long)
+		windows = append(windows, sloAlertingWindow{
+			Severity:    w.severity,
+			LongWindow:  w.long,
+			ShortWindow: w.short,
+			BurnRate:    math.Round(burnRate*10000) / 10000,
+		})
+	}
+	return windows
+}
+
+// validate returns an error for each inconsistent setting of the SLO
+func (s slo) validate() []error {
+	var errs []error
+	if s.Name == "" {
+		errs = append(errs, errors.New("the name of the SLO must not be empty"))
+	}
+	switch {
+	case s.ErrorRatioQuery != "" && (s.GoodQuery != "" || s.TotalQuery != ""):
+		errs = append(errs, errors.New("error_ratio_query can't be used with good_query and total_query"))
+	case s.ErrorRatioQuery == "" && (s.GoodQuery == "" || s.TotalQuery == ""):
+		errs = append(errs, errors.New("either error_ratio_query or both good_query and total_query must be set"))
+	}
+	for _, query := range []struct{ name, query string }{
+		{"good_query", s.GoodQuery},
+		{"total_query", s.TotalQuery},
+		{"error_ratio_query", s.ErrorRatioQuery},
+	} {
+		name, query := query.name, query.query
+		if query == "" {
+			continue
+		}
+		if !strings.Contains(query, sloWindowPlaceholder) {
+			errs = append(errs, fmt.Errorf("%s must use %s as the range of its range vector selectors, e.g. rate(http_requests_total[%s])", name, sloWindowPlaceholder, sloWindowPlaceholder))
+			continue
+		}
+		if _, err := parser.ParseExpr(strings.ReplaceAll(query, sloWindowPlaceholder, sloBaseWindow.String())); err != nil {
+			errs = append(errs, fmt.Errorf("%s is not a valid PromQL expression: %w", name, err))
+		}
+	}
+	if s.Objective <= 0 || s.Objective >= 100 {
+		errs = append(errs, fmt.Errorf("the objective must be a percentage between 0 and 100 excluded, got %s", formatSLONumber(s.Objective)))
+	}
+	if len(s.AlertingWindows) == 0 {
+		errs = append(errs, errors.New("at least one alerting window is required"))
+	}
+	for i, w := range s.AlertingWindows {
+		if w.Severity == "" {
+			errs = append(errs, fmt.Errorf("alerting window %d: the severity must not be empty", i))
+		}
+		if w.ShortWindow <= 0 || w.ShortWindow >= w.LongWindow {
+			errs = append(errs, fmt.Errorf("alerting window %d: the short window (%s) must be shorter than the long window (%s)", i, w.ShortWindow, w.LongWindow))
+		}
+		if w.LongWindow > s.TimeWindow {
+			errs = append(errs, fmt.Errorf("alerting window %d: the long window (%s) must not exceed the time window (%s)", i, w.LongWindow, s.TimeWindow))
+		}
+		if w.BurnRate <= 0 {
+			errs = append(errs, fmt.Errorf("alerting window %d: the burn rate must be positive, got %s", i, formatSLONumber(w.BurnRate)))
+		}
+	}
+	return errs
+}
+
+// ruleGroupNames returns the names of the generated rule groups, they are
+// named after the SLO so that several SLOs can share a namespace
+func (s slo) ruleGroupNames() map[string]bool {
+	return map[string]bool{
+		s.Name + "-sli-recordings":  true,
+		s.Name + "-meta-recordings": true,
+		s.Name + "-alerts":          true,
+	}
+}
+
+// ruleGroups generates the recording rules of the error ratio over each
+// window, the error budget metadata and the multiwindow, multi-burn-rate
+// alerts of the SLO. The recording rules follow the level:metric:operations
+// naming convention.
+func (s slo) ruleGroups() ([]rwrulefmt.RuleGroup, error) {
+	if errs := s.validate(); len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	selector := "{" + labels.MustNewMatcher(labels.MatchEqual, "slo", s.Name).String() + "}"
+	objective := formatSLONumber(s.Objective / 100)
+	errorBudget := fmt.Sprintf("(1 - %s)", objective)
+	errorRatio := func(window model.Duration) string { return "slo:sli_error:ratio_rate" + window.String() }
+
+	windows := []model.Duration{sloBaseWindow}
+	for _, w := range s.AlertingWindows {
+		windows = append(windows, w.LongWindow, w.ShortWindow)
+	}
+	slices.Sort(windows)
+	windows = slices.Compact(windows)
+
+	var sli []rulefmt.RuleNode
+	for _, window := range windows {
+		sli = append(sli, s.recordingRule(errorRatio(window), s.errorRatioExpr(window)))
+	}
+	if !slices.Contains(windows, s.TimeWindow) {
+		base := errorRatio(sloBaseWindow) + selector
+		sli = append(sli, s.recordingRule(errorRatio(s.TimeWindow), fmt.Sprintf("sum_over_time(%s[%s])\n/\ncount_over_time(%s[%s])", base, s.TimeWindow, base, s.TimeWindow)))
+	}
+
+	meta := []rulefmt.RuleNode{
+		s.recordingRule("slo:objective:ratio", fmt.Sprintf("vector(%s)", objective)),
+		s.recordingRule("slo:error_budget:ratio", fmt.Sprintf("vector%s", errorBudget)),
+		s.recordingRule("slo:time_period:days", fmt.Sprintf("vector(%s)", formatSLONumber(time.Duration(s.TimeWindow).Hours()/24))),
+		s.recordingRule("slo:current_burn_rate:ratio", fmt.Sprintf("%s%s\n/ on(slo) group_left\nslo:error_budget:ratio%s", errorRatio(sloBaseWindow), selector, selector)),
+		s.recordingRule("slo:period_burn_rate:ratio", fmt.Sprintf("%s%s\n/ on(slo) group_left\nslo:error_budget:ratio%s", errorRatio(s.TimeWindow), selector, selector)),
+		s.recordingRule("slo:period_error_budget_remaining:ratio", fmt.Sprintf("1 - slo:period_burn_rate:ratio%s", selector)),
+	}
+
+	// One alert per severity, firing when any of its windows burns too fast
+	var severities []string
+	conditions := map[string][]string{}
+	for _, w := range s.AlertingWindows {
+		if _, ok := conditions[w.Severity]; !ok {
+			severities = append(severities, w.Severity)
+		}
+		threshold := fmt.Sprintf("(%s * %s)", formatSLONumber(w.BurnRate), errorBudget)
+		conditions[w.Severity] = append(conditions[w.Severity], fmt.Sprintf("(\n  %s%s > %s\nand\n  %s%s > %s\n)",
+			errorRatio(w.LongWindow), selector, threshold, errorRatio(w.ShortWindow), selector, threshold))
+	}
+	var alerts []rulefmt.RuleNode
+	for _, severity := range severities {
+		ruleLabels := s.ruleLabels()
+		ruleLabels["severity"] = severity
+		annotations := map[string]string{
+			"summary": fmt.Sprintf("The %s SLO is burning its error budget too fast.", s.Name),
+		}
+		maps.Copy(annotations, s.Annotations)
+		alerts = append(alerts, rulefmt.RuleNode{
+			Alert:       yamlString(s.AlertName),
+			Expr:        yamlString(strings.Join(conditions[severity], "\nor\n")),
+			Labels:      ruleLabels,
+			Annotations: annotations,
+		})
+	}
+
+	groups := []rwrulefmt.RuleGroup{
+		{RuleGroup: rulefmt.RuleGroup{Name: s.Name + "-sli-recordings", Rules: sli}},
+		{RuleGroup: rulefmt.RuleGroup{Name: s.Name + "-meta-recordings", Rules: meta}},
+		{RuleGroup: rulefmt.RuleGroup{Name: s.Name + "-alerts", Rules: alerts}},
+	}
+	return groups, nil
+}
+
+// errorRatioExpr returns the expression of the error ratio over the window
+func (s slo) errorRatioExpr(window model.Duration) string {
+	withWindow := func(query string) string {
+		return strings.ReplaceAll(query, sloWindowPlaceholder, window.String())
+	}
+	if s.ErrorRatioQuery != "" {
+		return withWindow(s.ErrorRatioQuery)
+	}
+	return fmt.Sprintf("1 - (\n  (%s)\n/\n  (%s)\n)", withWindow(s.GoodQuery), withWindow(s.TotalQuery))
+}
+
+// recordingRule returns a recording rule of the SLO
+func (s slo) recordingRule(record, expr string) rulefmt.RuleNode {
+	return rulefmt.RuleNode{
+		Record: yamlString(record),
+		Expr:   yamlString(expr),
+		Labels: s.ruleLabels(),
+	}
+}
+
+// ruleLabels are the labels of the generated rules, slo identifies the SLO
+func (s slo) ruleLabels() map[string]string {
+	ruleLabels := map[string]string{}
+	maps.Copy(ruleLabels, s.DefaultLabels)
+	maps.Copy(ruleLabels, s.Labels)
+	ruleLabels["slo"] = s.Name
+	return ruleLabels
+}
+
+func yamlString(value string) yaml.Node {
+	return yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+// formatSLONumber formats the number without the floating point noise of
+// the computations, e.g. 99.9 / 100
+func formatSLONumber(value float64) string {
+	return strconv.FormatFloat(math.Round(value*1e10)/1e10, 'f', -1, 64)
+}
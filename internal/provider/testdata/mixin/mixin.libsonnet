@@ -0,0 +1,30 @@
+local selectors = import 'github.com/example/lib/selectors.libsonnet';
+
+{
+  _config+:: {
+    job: std.extVar('job'),
+  },
+
+  prometheusRules+:: {
+    groups+: [{
+      name: 'example_rules',
+      rules: [{
+        record: 'job:up:sum',
+        expr: 'sum by (job) (up{%s})' % selectors.jobSelector($._config.job),
+      }],
+    }],
+  },
+
+  prometheusAlerts+:: {
+    groups+: [{
+      name: 'example_alerts',
+      rules: [{
+        alert: 'ExampleDown',
+        expr: 'job:up:sum{%s} == 0' % selectors.jobSelector($._config.job),
+        'for': '5m',
+        labels: { severity: 'critical' },
+        annotations: { summary: 'The %s job is down.' % $._config.job },
+      }],
+    }],
+  },
+}
@@ -4,10 +4,28 @@ import (
 	context "context"
 
 	rwrulefmt "github.com/grafana/mimir/pkg/mimirtool/rules/rwrulefmt"
+	goversion "github.com/hashicorp/go-version"
 )
 
+// myClient is the data shared by the provider with its resources and data sources
 type myClient struct {
 	cli mimirClientInterface
+	// rulerClients are the clients of the ruler API of each backend, see rulerBackends
+	rulerClients map[string]mimirClientInterface
+	// mimirVersion is the version reported by the startup probe, nil when unknown
+	mimirVersion *goversion.Version
+	// features are the features reported by the startup probe, nil when unknown
+	features map[string]string
+	// federatedRules tells whether federated rule groups are enabled, nil when unknown
+	federatedRules *bool
+	// unsupportedRuleGroupFields is how rule group fields unsupported by mimirVersion are reported, warn or fail
+	unsupportedRuleGroupFields string
+	// defaultRuleLabels are merged into every rule of the ruler namespaces
+	defaultRuleLabels map[string]string
+	// rulePolicy is checked against the rules of the ruler namespaces, nil when not set
+	rulePolicy *rulePolicyModel
+	// rulerLimits are the limits of the tenant checked before writing to the Grafana Mimir ruler
+	rulerLimits *rulerLimitsSource
 }
 
 type mimirClientInterface interface {
@@ -3,8 +3,14 @@ package provider
 import (
 	"context"
 	"fmt"
+	"regexp"
+	"slices"
+	"strings"
 
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/prometheus/common/model"
 	"gopkg.in/yaml.v3"
 )
 
@@ -27,13 +33,132 @@ func (v namespaceYAMLValidator) ValidateString(ctx context.Context, req validato
 		// Let the non-empty validator handle this case
 		return
 	}
-	_, err := getRuleNamespaceFromYAML(ctx, req.ConfigValue.ValueString())
+
+	var resource RulerNamespaceResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &resource)...)
+	if resource.Backend.IsUnknown() {
+		// The query language of the expressions depends on the backend
+		return
+	}
+	validateNamespaceYAML(ctx, resource, req.ConfigValue.ValueString(), "", req.Path, &resp.Diagnostics)
+}
+
+// validateNamespaceYAML reports the issues of the rule groups of a namespace
+// YAML written for the resource, prefixed by the rule file they come from
+// when source is not empty.
+func validateNamespaceYAML(ctx context.Context, resource RulerNamespaceResourceModel, configYAML string, source string, attrPath path.Path, diagnostics *diag.Diagnostics) {
+	at := func(detail string) string {
+		if source == "" {
+			return detail
+		}
+		return fmt.Sprintf("%s: %s", source, detail)
+	}
+	backend := resource.rulerBackend()
+
+	// Report each invalid expression with its position, and the ones not
+	// canonically formatted depending on lint_mode
+	var issues []expressionIssue
+	if usesPromQL(backend) {
+		issues = lintRuleExpressions(configYAML)
+	}
+	invalidExpressions := false
+	for _, issue := range issues {
+		switch {
+		case issue.Err != nil:
+			invalidExpressions = true
+			diagnostics.AddAttributeError(attrPath, "Invalid PromQL expression", at(issue.String()))
+		case resource.LintMode.ValueString() == lintModeWarn:
+			diagnostics.AddAttributeWarning(attrPath, "PromQL expression not canonically formatted", at(issue.String()))
+		case resource.LintMode.ValueString() == lintModeFail:
+			diagnostics.AddAttributeError(attrPath, "PromQL expression not canonically formatted", at(issue.String()))
+		}
+	}
+	if invalidExpressions {
+		return
+	}
+
+	// Reject the expressions the selector matchers can't be injected in
+	if resource.SelectorMatchers.ValueString() != "" && usesPromQL(backend) {
+		matchers, err := parseSelectorMatchers(resource.SelectorMatchers.ValueString())
+		ruleNamespace, nsErr := getRuleNamespaceFromYAML(ctx, configYAML, backend)
+		if err == nil && nsErr == nil {
+			for _, err := range injectSelectorMatchers(ruleNamespace, matchers) {
+				diagnostics.AddAttributeError(attrPath, "Conflicting selector matchers", at(err.Error()))
+			}
+		}
+	}
+
+	ruleNamespace, err := getRuleNamespaceFromYAML(ctx, configYAML, backend)
 	if err != nil {
-		resp.Diagnostics.AddAttributeError(
-			req.Path,
+		diagnostics.AddAttributeError(
+			attrPath,
 			"Invalid namespace YAML",
-			fmt.Sprintf("Namespace definition is not valid: %s", err.Error()),
+			at(fmt.Sprintf("Namespace definition is not valid: %s", err.Error())),
 		)
+		return
+	}
+
+	for _, err := range checkRuleGroupOptions(ruleNamespace) {
+		diagnostics.AddAttributeError(attrPath, "Invalid rule group option", at(err.Error()))
+	}
+	for _, err := range checkSourceTenants(ruleNamespace) {
+		diagnostics.AddAttributeError(attrPath, "Invalid source tenants", at(err.Error()))
+	}
+
+	// Check the rules with the annotations merged by the resource against its
+	// rule policy, the attributes depending on the labels are checked at plan time
+	if resource.RulePolicy != nil && !resource.ExtraAnnotations.IsUnknown() {
+		mergeRuleLabels(ruleNamespace, nil, stringMapValue(resource.ExtraAnnotations))
+		policy := resource.RulePolicy.withoutLabelChecks()
+		violations := policy.check(ruleNamespace)
+		for i := range violations {
+			violations[i] = at(violations[i])
+		}
+		addRulePolicyDiagnostics(diagnostics, attrPath, policy, violations)
+	}
+}
+
+// configFilesValidator checks each rule file of config_files like config_yaml,
+// so that the issues are reported with their position in the file, and that
+// a group name is not used by several files
+
+type configFilesValidator struct{}
+
+func (v configFilesValidator) Description(_ context.Context) string {
+	return "Validates that the files are valid rule files defining distinct rule groups"
+}
+
+func (v configFilesValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v configFilesValidator) ValidateList(ctx context.Context, req validator.ListRequest, resp *validator.ListResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	var patterns []string
+	resp.Diagnostics.Append(req.ConfigValue.ElementsAs(ctx, &patterns, false)...)
+	var resource RulerNamespaceResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &resource)...)
+	if resp.Diagnostics.HasError() || resource.Backend.IsUnknown() {
+		return
+	}
+
+	files, err := readConfigFiles(patterns)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(req.Path, "Unable to read rule files", err.Error())
+		return
+	}
+	for _, file := range files {
+		if strings.TrimSpace(file.Content) != "" {
+			validateNamespaceYAML(ctx, resource, file.Content, file.Path, req.Path, &resp.Diagnostics)
+		}
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if _, _, err := mergeRuleFiles(ctx, files, resource.rulerBackend()); err != nil {
+		resp.Diagnostics.AddAttributeError(req.Path, "Invalid rule files", err.Error())
 	}
 }
 
@@ -63,3 +188,206 @@ func (v yamlSyntaxValidator) ValidateString(_ context.Context, req validator.Str
 		)
 	}
 }
+
+// stringOneOfValidator checks that a string is one of the allowed values
+
+type stringOneOfValidator struct {
+	values []string
+}
+
+func (v stringOneOfValidator) Description(_ context.Context) string {
+	return fmt.Sprintf("Ensures the value is one of: %s", strings.Join(v.values, ", "))
+}
+
+func (v stringOneOfValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v stringOneOfValidator) ValidateString(_ context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	if !slices.Contains(v.values, req.ConfigValue.ValueString()) {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Invalid value",
+			fmt.Sprintf("%q is not one of: %s", req.ConfigValue.ValueString(), strings.Join(v.values, ", ")),
+		)
+	}
+}
+
+// labelNameValidator checks that a string is a valid Prometheus label name
+
+type labelNameValidator struct{}
+
+func (v labelNameValidator) Description(_ context.Context) string {
+	return "Ensures the value is a valid Prometheus label name"
+}
+
+func (v labelNameValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v labelNameValidator) ValidateString(_ context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	if !model.LabelName(req.ConfigValue.ValueString()).IsValid() {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Invalid label name",
+			fmt.Sprintf("%q is not a valid label name, it must match %s", req.ConfigValue.ValueString(), model.LabelNameRE),
+		)
+	}
+}
+
+// selectorMatchersValidator checks that a string is a PromQL selector made of label matchers, e.g. {cluster="prod"}
+
+type selectorMatchersValidator struct{}
+
+func (v selectorMatchersValidator) Description(_ context.Context) string {
+	return "Ensures the value is a PromQL selector such as {cluster=\"prod\"}"
+}
+
+func (v selectorMatchersValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v selectorMatchersValidator) ValidateString(_ context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	if _, err := parseSelectorMatchers(req.ConfigValue.ValueString()); err != nil {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Invalid selector matchers",
+			err.Error(),
+		)
+	}
+}
+
+// labelNamesMapValidator checks that the keys of a map are valid Prometheus label names
+
+type labelNamesMapValidator struct{}
+
+func (v labelNamesMapValidator) Description(_ context.Context) string {
+	return "Ensures the keys are valid Prometheus label names"
+}
+
+func (v labelNamesMapValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v labelNamesMapValidator) ValidateMap(_ context.Context, req validator.MapRequest, resp *validator.MapResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	for name := range req.ConfigValue.Elements() {
+		if !model.LabelName(name).IsValid() {
+			resp.Diagnostics.AddAttributeError(
+				req.Path.AtMapKey(name),
+				"Invalid label name",
+				fmt.Sprintf("%q is not a valid label name, it must match %s", name, model.LabelNameRE),
+			)
+		}
+	}
+}
+
+// durationValidator checks that a string is a Prometheus duration, e.g. 1m or 1h30m
+
+type durationValidator struct{}
+
+func (v durationValidator) Description(_ context.Context) string {
+	return "Ensures the value is a duration such as 1m or 1h30m"
+}
+
+func (v durationValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v durationValidator) ValidateString(_ context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	if _, err := model.ParseDuration(req.ConfigValue.ValueString()); err != nil {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Invalid duration",
+			err.Error(),
+		)
+	}
+}
+
+// regexpSetValidator checks that every element of a set is a valid regular expression
+
+type regexpSetValidator struct{}
+
+func (v regexpSetValidator) Description(_ context.Context) string {
+	return "Ensures the elements are valid regular expressions"
+}
+
+func (v regexpSetValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v regexpSetValidator) ValidateSet(_ context.Context, req validator.SetRequest, resp *validator.SetResponse) {
+	for _, expr := range stringSetValue(req.ConfigValue) {
+		if _, err := regexp.Compile(expr); err != nil {
+			resp.Diagnostics.AddAttributeError(
+				req.Path,
+				"Invalid regular expression",
+				err.Error(),
+			)
+		}
+	}
+}
+
+// ruleTestsValidator checks that a string is in the promtool rule unit tests format
+
+type ruleTestsValidator struct{}
+
+func (v ruleTestsValidator) Description(_ context.Context) string {
+	return "Ensures the value is in the promtool rule unit tests format"
+}
+
+func (v ruleTestsValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v ruleTestsValidator) ValidateString(_ context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	if _, err := parseRuleTests(req.ConfigValue.ValueString()); err != nil {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Invalid rule tests",
+			err.Error(),
+		)
+	}
+}
+
+// nonNegativeInt64Validator checks that a number is positive or zero
+
+type nonNegativeInt64Validator struct{}
+
+func (v nonNegativeInt64Validator) Description(_ context.Context) string {
+	return "Ensures the value is positive or zero"
+}
+
+func (v nonNegativeInt64Validator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v nonNegativeInt64Validator) ValidateInt64(_ context.Context, req validator.Int64Request, resp *validator.Int64Response) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	if req.ConfigValue.ValueInt64() < 0 {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Invalid value",
+			fmt.Sprintf("The value must be positive or zero, got %d.", req.ConfigValue.ValueInt64()),
+		)
+	}
+}